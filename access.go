@@ -34,6 +34,11 @@ func (l *logFilter) Filter(ctx *context.Context) bool {
 			return true
 		}
 	}
+	for prefix := range staticFS {
+		if strings.HasPrefix(requestPath, prefix) {
+			return true
+		}
+	}
 	return false
 }
 
@@ -0,0 +1,80 @@
+package gon
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"strings"
+	"sync"
+	"time"
+)
+
+// assetHash content hash of static file
+type assetHash struct {
+	modTime time.Time
+	size    int64
+	hash    string
+}
+
+var (
+	assetHashes    = make(map[string]assetHash)
+	assetHashLock  sync.RWMutex
+	errAssetNotDir = errors.New("asset is a directory")
+)
+
+// AssetURL returns url of static file urlPath with its content hash as v query
+// e.g. /static/css/app.css?v=9f86d081884c7d65, files are found in StaticDir or StaticFS.
+// Responses of fingerprinted urls are cached by clients for a year
+func AssetURL(urlPath string) (string, error) {
+	clean := path.Clean("/" + urlPath)
+	fsys, name, fi, err := searchPath(clean)
+	if fi == nil {
+		if err == nil || err == errNotStaticRequest {
+			err = os.ErrNotExist
+		}
+		return urlPath, err
+	}
+	if fi.IsDir() {
+		return urlPath, errAssetNotDir
+	}
+	hash := fileHash(fsys, name, fi)
+	if hash == "" {
+		return urlPath, os.ErrNotExist
+	}
+	sep := "?"
+	if strings.Contains(urlPath, "?") {
+		sep = "&"
+	}
+	return urlPath + sep + "v=" + hash, nil
+}
+
+// fileHash returns hex of first 8 bytes of sha256 of file name in fsys
+// hashes are recomputed once file changes
+func fileHash(fsys fs.FS, name string, fi os.FileInfo) string {
+	key := cacheKey(fsys, name)
+	assetHashLock.RLock()
+	h, ok := assetHashes[key]
+	assetHashLock.RUnlock()
+	if ok && h.modTime.Equal(fi.ModTime()) && h.size == fi.Size() {
+		return h.hash
+	}
+
+	f, err := fsys.Open(name)
+	if err != nil {
+		return ""
+	}
+	defer f.Close()
+	sum := sha256.New()
+	if _, err := io.Copy(sum, f); err != nil {
+		return ""
+	}
+	h = assetHash{modTime: fi.ModTime(), size: fi.Size(), hash: hex.EncodeToString(sum.Sum(nil)[:8])}
+	assetHashLock.Lock()
+	assetHashes[key] = h
+	assetHashLock.Unlock()
+	return h.hash
+}
@@ -0,0 +1,131 @@
+// Package auth authenticates requests with pluggable strategies
+// Usage:
+//
+//	auth.Use(
+//		&auth.SessionStrategy{Load: loadUser},
+//		&auth.BearerStrategy{Validate: userOfToken},
+//	)
+//
+//	mux.Route("/account", &AccountController{}).Auth()
+//	api := mux.PathPrefix("/api").Subrouter().Auth(&auth.APIKeyStrategy{Validate: clientOfKey})
+//
+// then in controller:
+//
+//	user := this.CurrentUser().(*User)
+package auth
+
+import (
+	"errors"
+
+	"github.com/mellowarex/gon/context"
+)
+
+var (
+	// ErrNoCredentials request has not credentials of strategy, next strategy is tried
+	ErrNoCredentials = errors.New("auth: no credentials")
+	// ErrInvalidCredentials credentials of request are wrong
+	ErrInvalidCredentials = errors.New("auth: invalid credentials")
+)
+
+// context input data keys
+const (
+	userKey    = "auth.user"
+	checkedKey = "auth.checked"
+)
+
+// Strategy authenticates requests by one kind of credentials
+type Strategy interface {
+	// Authenticate returns user of request
+	// ErrNoCredentials is returned if request has not credentials of strategy,
+	// other errors when they are invalid
+	Authenticate(ctx *context.Context) (interface{}, error)
+}
+
+// Challenger is implemented by strategies which tell client
+// how to authenticate on 401 responses e.g. WWW-Authenticate: Basic
+type Challenger interface {
+	Challenge(ctx *context.Context)
+}
+
+// Default strategies used by User and guards without strategies
+// users logged in with Controller.Login by default
+var Default = []Strategy{&SessionStrategy{}}
+
+// Use sets Default strategies, tried in order
+func Use(strategies ...Strategy) {
+	Default = strategies
+}
+
+// Authenticate returns user of first strategy finding credentials in request
+// invalid credentials are not passed to next strategies
+func Authenticate(ctx *context.Context, strategies ...Strategy) (interface{}, error) {
+	if len(strategies) == 0 {
+		strategies = Default
+	}
+	for _, s := range strategies {
+		user, err := s.Authenticate(ctx)
+		if err == ErrNoCredentials {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		if user != nil {
+			return user, nil
+		}
+	}
+	return nil, ErrNoCredentials
+}
+
+// Require authenticates request and keeps user for User
+// on failure challenges of strategies are set to response
+func Require(ctx *context.Context, strategies ...Strategy) (interface{}, error) {
+	if user, ok := cached(ctx); ok && user != nil {
+		return user, nil
+	}
+	user, err := Authenticate(ctx, strategies...)
+	if err != nil {
+		if len(strategies) == 0 {
+			strategies = Default
+		}
+		for _, s := range strategies {
+			if c, ok := s.(Challenger); ok {
+				c.Challenge(ctx)
+			}
+		}
+		return nil, err
+	}
+	SetUser(ctx, user)
+	return user, nil
+}
+
+// User returns user of request, authenticated by Default strategies once
+// nil is returned for anonymous requests
+func User(ctx *context.Context) interface{} {
+	if user, ok := cached(ctx); ok {
+		return user
+	}
+	user, _ := Authenticate(ctx)
+	SetUser(ctx, user)
+	return user
+}
+
+// SetUser sets user of request e.g. after login, nil for anonymous
+func SetUser(ctx *context.Context, user interface{}) {
+	ctx.Input.SetData(userKey, user)
+	ctx.Input.SetData(checkedKey, true)
+}
+
+// Clear forgets user of request, User authenticates again
+func Clear(ctx *context.Context) {
+	ctx.Input.SetData(userKey, nil)
+	ctx.Input.SetData(checkedKey, false)
+}
+
+// cached returns user kept for request
+func cached(ctx *context.Context) (interface{}, bool) {
+	if checked, _ := ctx.Input.GetData(checkedKey).(bool); !checked {
+		return nil, false
+	}
+	return ctx.Input.GetData(userKey), true
+}
@@ -0,0 +1,215 @@
+package auth
+
+import (
+	"errors"
+	"sync"
+
+	"github.com/mellowarex/gon/context"
+)
+
+// ErrForbidden user is not allowed to perform action
+var ErrForbidden = errors.New("auth: forbidden")
+
+// Authorizer decides what users are allowed to do
+type Authorizer interface {
+	// HasRole reports whether user has role
+	HasRole(user interface{}, role string) bool
+	// Can reports whether user may perform action, on resource if not nil
+	Can(user interface{}, action string, resource interface{}) bool
+}
+
+// Authz authorizer of route policies, Controller.Can and template helpers
+var Authz Authorizer = NewRBAC(nil)
+
+// Policy requirements of route or controller method
+// user must have one of Roles, if any, and all of Permissions
+type Policy struct {
+	Roles       []string
+	Permissions []string
+}
+
+// Allows reports whether policy allows user
+func (p Policy) Allows(a Authorizer, user interface{}) bool {
+	if len(p.Roles) > 0 {
+		ok := false
+		for _, role := range p.Roles {
+			if a.HasRole(user, role) {
+				ok = true
+				break
+			}
+		}
+		if !ok {
+			return false
+		}
+	}
+	for _, perm := range p.Permissions {
+		if !a.Can(user, perm, nil) {
+			return false
+		}
+	}
+	return true
+}
+
+// Authorize checks user of request against policies
+// ErrNoCredentials is returned for anonymous requests, ErrForbidden when denied
+func Authorize(ctx *context.Context, policies ...Policy) error {
+	user, err := Require(ctx)
+	if err != nil {
+		return err
+	}
+	for _, p := range policies {
+		if !p.Allows(Authz, user) {
+			return ErrForbidden
+		}
+	}
+	return nil
+}
+
+// Can reports whether user of request may perform action, on resource if given
+func Can(ctx *context.Context, action string, resource ...interface{}) bool {
+	user := User(ctx)
+	if user == nil {
+		return false
+	}
+	var res interface{}
+	if len(resource) > 0 {
+		res = resource[0]
+	}
+	return Authz.Can(user, action, res)
+}
+
+// HasRole reports whether user of request has role
+func HasRole(ctx *context.Context, role string) bool {
+	user := User(ctx)
+	return user != nil && Authz.HasRole(user, role)
+}
+
+// RoleHolder is implemented by users knowing their roles
+type RoleHolder interface {
+	Roles() []string
+}
+
+// Rule decides access of user to resource regardless of permissions of roles
+// e.g. authors may edit their own posts
+type Rule func(user, resource interface{}) bool
+
+// RBAC in-memory role based Authorizer
+// usage:
+//
+//	rbac := auth.NewRBAC(nil) // users implement auth.RoleHolder
+//	rbac.Grant("editor", "posts.edit", "posts.publish")
+//	rbac.Inherit("admin", "editor")
+//	rbac.Grant("admin", "users.manage")
+//	rbac.Rule("posts.edit", func(user, post interface{}) bool {
+//		return post.(*Post).AuthorID == user.(*User).ID
+//	})
+//	auth.Authz = rbac
+type RBAC struct {
+	roles func(user interface{}) []string
+
+	lock    sync.RWMutex
+	grants  map[string]map[string]bool
+	parents map[string][]string
+	rules   map[string][]Rule
+}
+
+// NewRBAC returns RBAC reading roles of users by roles func
+// users must implement RoleHolder if roles is nil
+func NewRBAC(roles func(user interface{}) []string) *RBAC {
+	return &RBAC{
+		roles:   roles,
+		grants:  make(map[string]map[string]bool),
+		parents: make(map[string][]string),
+		rules:   make(map[string][]Rule),
+	}
+}
+
+// Grant gives permissions to role, "*" grants every permission
+func (r *RBAC) Grant(role string, permissions ...string) *RBAC {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	if r.grants[role] == nil {
+		r.grants[role] = make(map[string]bool)
+	}
+	for _, p := range permissions {
+		r.grants[role][p] = true
+	}
+	return r
+}
+
+// Revoke takes permissions from role
+func (r *RBAC) Revoke(role string, permissions ...string) *RBAC {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	for _, p := range permissions {
+		delete(r.grants[role], p)
+	}
+	return r
+}
+
+// Inherit gives role the roles and permissions of parents
+func (r *RBAC) Inherit(role string, parents ...string) *RBAC {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	r.parents[role] = append(r.parents[role], parents...)
+	return r
+}
+
+// Rule adds rule allowing action on resources,
+// tried when roles of user lack the permission
+func (r *RBAC) Rule(action string, rule Rule) *RBAC {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	r.rules[action] = append(r.rules[action], rule)
+	return r
+}
+
+// HasRole implements Authorizer
+// roles inherited by roles of user count
+func (r *RBAC) HasRole(user interface{}, role string) bool {
+	r.lock.RLock()
+	defer r.lock.RUnlock()
+	return r.expand(user)[role]
+}
+
+// Can implements Authorizer
+func (r *RBAC) Can(user interface{}, action string, resource interface{}) bool {
+	r.lock.RLock()
+	defer r.lock.RUnlock()
+	for role := range r.expand(user) {
+		if r.grants[role][action] || r.grants[role]["*"] {
+			return true
+		}
+	}
+	if resource == nil {
+		return false
+	}
+	for _, rule := range r.rules[action] {
+		if rule(user, resource) {
+			return true
+		}
+	}
+	return false
+}
+
+// expand returns roles of user with inherited ones
+func (r *RBAC) expand(user interface{}) map[string]bool {
+	var roles []string
+	if r.roles != nil {
+		roles = r.roles(user)
+	} else if h, ok := user.(RoleHolder); ok {
+		roles = h.Roles()
+	}
+	roles = append([]string(nil), roles...)
+	all := make(map[string]bool)
+	for len(roles) > 0 {
+		role := roles[len(roles)-1]
+		roles = roles[:len(roles)-1]
+		if all[role] {
+			continue
+		}
+		all[role] = true
+		roles = append(roles, r.parents[role]...)
+	}
+	return all
+}
@@ -0,0 +1,62 @@
+package auth
+
+import (
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/mellowarex/gon/context"
+)
+
+// remember-me cookie settings, set from WebConfig by gon
+var (
+	// RememberName name of remember-me cookie
+	RememberName = "gon_remember"
+	// RememberKey secret signing remember-me cookies
+	// remember-me is off when empty
+	RememberKey = ""
+)
+
+// RegenerateSession gives request a new session id keeping its values
+// set by gon when sessions are on, used when login is restored by remember-me
+var RegenerateSession func(ctx *context.Context) error
+
+// Remember sets remember-me cookie of user id valid for maxAge seconds
+// the cookie restores login of SessionStrategy once session is gone
+func Remember(ctx *context.Context, id string, maxAge int64) bool {
+	if RememberKey == "" || id == "" || maxAge <= 0 {
+		return false
+	}
+	expires := time.Now().Unix() + maxAge
+	value := id + "|" + strconv.FormatInt(expires, 10)
+	ctx.SetSecureCookie(RememberKey, RememberName, value, maxAge, "/", "", ctx.Input.IsSecure(), true)
+	return true
+}
+
+// Remembered returns user id of valid remember-me cookie of request
+func Remembered(ctx *context.Context) string {
+	if RememberKey == "" {
+		return ""
+	}
+	value, ok := ctx.GetSecureCookie(RememberKey, RememberName)
+	if !ok {
+		return ""
+	}
+	i := strings.LastIndexByte(value, '|')
+	if i <= 0 {
+		return ""
+	}
+	expires, err := strconv.ParseInt(value[i+1:], 10, 64)
+	if err != nil || time.Now().Unix() > expires {
+		return ""
+	}
+	return value[:i]
+}
+
+// Forget deletes remember-me cookie
+func Forget(ctx *context.Context) {
+	if ctx.Input.GetCookie(RememberName) == "" {
+		return
+	}
+	ctx.SetCookie(RememberName, "", -1, "/", "", ctx.Input.IsSecure(), true)
+}
@@ -0,0 +1,152 @@
+package auth
+
+import (
+	context2 "context"
+	"fmt"
+	"strings"
+
+	"github.com/mellowarex/gon/context"
+	"github.com/mellowarex/gon/session"
+)
+
+// SessionStrategy authenticates user logged in with Controller.Login
+// by session key session.UserIDKey, or by remember-me cookie
+type SessionStrategy struct {
+	// Load returns user of id, id itself is user if nil
+	// ids of remember-me cookies are given in string form
+	Load func(id string) (interface{}, error)
+}
+
+// Authenticate implements Strategy
+func (s *SessionStrategy) Authenticate(ctx *context.Context) (interface{}, error) {
+	var id string
+	if store := ctx.Input.Cookie; store != nil {
+		if v := store.Get(context2.Background(), session.UserIDKey); v != nil {
+			id = fmt.Sprint(v)
+		}
+	}
+	remembered := false
+	if id == "" {
+		if id = Remembered(ctx); id == "" {
+			return nil, ErrNoCredentials
+		}
+		remembered = true
+	}
+	var user interface{} = id
+	if s.Load != nil {
+		u, err := s.Load(id)
+		if err != nil || u == nil {
+			if remembered {
+				Forget(ctx)
+			}
+			return nil, ErrInvalidCredentials
+		}
+		user = u
+	}
+	if remembered {
+		restoreLogin(ctx, id)
+	}
+	return user, nil
+}
+
+// restoreLogin logs remembered user in session of request
+// a new session id is used as on Controller.Login
+func restoreLogin(ctx *context.Context, id string) {
+	if ctx.Input.Cookie == nil {
+		return
+	}
+	if RegenerateSession != nil {
+		if err := RegenerateSession(ctx); err != nil {
+			return
+		}
+	}
+	ctx.Input.Cookie.Set(context2.Background(), session.UserIDKey, id, ctx.Request, ctx.ResponseWriter)
+}
+
+// BasicStrategy authenticates by HTTP Basic authorization
+type BasicStrategy struct {
+	Realm    string
+	Validate func(username, password string) (interface{}, error)
+}
+
+// Authenticate implements Strategy
+func (s *BasicStrategy) Authenticate(ctx *context.Context) (interface{}, error) {
+	username, password, ok := ctx.Request.BasicAuth()
+	if !ok {
+		return nil, ErrNoCredentials
+	}
+	return validate(s.Validate(username, password))
+}
+
+// Challenge implements Challenger
+func (s *BasicStrategy) Challenge(ctx *context.Context) {
+	realm := s.Realm
+	if realm == "" {
+		realm = "Restricted"
+	}
+	ctx.Output.Header("WWW-Authenticate", fmt.Sprintf(`Basic realm=%q, charset="UTF-8"`, realm))
+}
+
+// BearerStrategy authenticates by Authorization: Bearer token
+type BearerStrategy struct {
+	Realm    string
+	Validate func(token string) (interface{}, error)
+}
+
+// BearerToken returns bearer token of Authorization header of request
+func BearerToken(ctx *context.Context) string {
+	h := ctx.Request.Header.Get("Authorization")
+	if len(h) > 7 && strings.EqualFold(h[:7], "Bearer ") {
+		return strings.TrimSpace(h[7:])
+	}
+	return ""
+}
+
+// Authenticate implements Strategy
+func (s *BearerStrategy) Authenticate(ctx *context.Context) (interface{}, error) {
+	token := BearerToken(ctx)
+	if token == "" {
+		return nil, ErrNoCredentials
+	}
+	return validate(s.Validate(token))
+}
+
+// Challenge implements Challenger
+func (s *BearerStrategy) Challenge(ctx *context.Context) {
+	if s.Realm != "" {
+		ctx.Output.Header("WWW-Authenticate", fmt.Sprintf("Bearer realm=%q", s.Realm))
+		return
+	}
+	ctx.Output.Header("WWW-Authenticate", "Bearer")
+}
+
+// APIKeyStrategy authenticates by key in request header or query
+type APIKeyStrategy struct {
+	Header   string // header holding key, X-API-Key by default
+	Query    string // query parameter holding key, not read if empty
+	Validate func(key string) (interface{}, error)
+}
+
+// Authenticate implements Strategy
+func (s *APIKeyStrategy) Authenticate(ctx *context.Context) (interface{}, error) {
+	header := s.Header
+	if header == "" {
+		header = "X-API-Key"
+	}
+	key := ctx.Request.Header.Get(header)
+	if key == "" && s.Query != "" {
+		key = ctx.Request.URL.Query().Get(s.Query)
+	}
+	if key == "" {
+		return nil, ErrNoCredentials
+	}
+	return validate(s.Validate(key))
+}
+
+// validate maps failed validation to ErrInvalidCredentials
+func validate(user interface{}, err error) (interface{}, error) {
+	if err != nil || user == nil {
+		return nil, ErrInvalidCredentials
+	}
+	return user, nil
+}
@@ -0,0 +1,294 @@
+// Package cache provides server-side response cache for routes
+// Usage:
+//
+//	gon.ResponseCache = cache.New(cache.NewMemoryStore(1024))
+//	mux.Route("/posts", &PostsController{}).Cache(cache.Policy{
+//		TTL:     time.Minute,
+//		Headers: []string{"Accept-Language"},
+//		Tags:    []string{"posts"},
+//	})
+//
+// then invalidate after writes:
+//
+//	gon.ResponseCache.Invalidate("posts")
+package cache
+
+import (
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/mellowarex/gon/context"
+)
+
+// Entry is a cached response
+type Entry struct {
+	Status  int
+	Header  http.Header
+	Body    []byte
+	Tags    []string
+	Vary    []string // request headers the response varies on
+	Created time.Time
+	Expires time.Time
+}
+
+// Expired reports whether entry ttl has passed
+func (e *Entry) Expired() bool {
+	return !e.Expires.IsZero() && time.Now().After(e.Expires)
+}
+
+// Store defines cache backend behavior
+type Store interface {
+	Get(key string) (*Entry, bool)
+	Set(key string, e *Entry) error
+	Delete(key string) error
+}
+
+// Evictor is implemented by stores which drop entries by themselves,
+// e.g. MemoryStore when it is full. Cache removes evicted keys from its tag index
+type Evictor interface {
+	OnEvict(fn func(key string))
+}
+
+// Policy is per route cache configuration
+type Policy struct {
+	TTL         time.Duration
+	Headers     []string // request headers which are part of cache key
+	IgnoreQuery bool     // if true, url query is not part of cache key
+	Tags        []string // tags added to every entry of route
+}
+
+// Key returns cache key of request built from
+// method, path, selected headers and sorted query
+func (p *Policy) Key(r *http.Request) string {
+	var b strings.Builder
+	b.WriteString(r.Method)
+	b.WriteByte(' ')
+	b.WriteString(r.URL.Path)
+	if !p.IgnoreQuery && r.URL.RawQuery != "" {
+		b.WriteByte('?')
+		b.WriteString(sortedQuery(r.URL.Query()))
+	}
+	for _, h := range p.Headers {
+		b.WriteByte('\n')
+		b.WriteString(http.CanonicalHeaderKey(h))
+		b.WriteByte(':')
+		b.WriteString(r.Header.Get(h))
+	}
+	return b.String()
+}
+
+func sortedQuery(q url.Values) string {
+	for _, v := range q {
+		sort.Strings(v)
+	}
+	// url.Values.Encode sorts by key
+	return q.Encode()
+}
+
+// Cache is response cache over a Store
+// it keeps a tag index to invalidate entries by tag
+// and protects backends from stampede with single flight.
+// Keys leave the index when deleted, evicted by store or found expired
+type Cache struct {
+	store Store
+
+	lock    sync.Mutex
+	tags    map[string]map[string]struct{} // tag -> keys
+	keys    map[string]indexed             // key -> its tags
+	sweepAt int                            // size of keys to drop expired ones at
+
+	flightLock sync.Mutex
+	flights    map[string]*flight
+}
+
+// indexed tags and expiry of key in tag index
+type indexed struct {
+	tags    []string
+	expires time.Time
+}
+
+// minSweep is the least size of tag index swept for expired keys
+const minSweep = 1024
+
+type flight struct {
+	wg    sync.WaitGroup
+	entry *Entry
+}
+
+// New returns Cache backed by store
+func New(store Store) *Cache {
+	c := &Cache{
+		store:   store,
+		tags:    make(map[string]map[string]struct{}),
+		keys:    make(map[string]indexed),
+		sweepAt: minSweep,
+		flights: make(map[string]*flight),
+	}
+	if ev, ok := store.(Evictor); ok {
+		ev.OnEvict(c.untag)
+	}
+	return c
+}
+
+// Get returns unexpired entry for key
+func (c *Cache) Get(key string) (*Entry, bool) {
+	e, ok := c.store.Get(key)
+	if !ok || e == nil {
+		return nil, false
+	}
+	if e.Expired() {
+		c.Delete(key)
+		return nil, false
+	}
+	return e, true
+}
+
+// Set stores entry with key and indexes its tags
+// expired keys are swept from index as it grows, their entries
+// are dropped by Get
+func (c *Cache) Set(key string, e *Entry) error {
+	if err := c.store.Set(key, e); err != nil {
+		return err
+	}
+	c.lock.Lock()
+	c.untagLocked(key)
+	if len(e.Tags) > 0 {
+		for _, tag := range e.Tags {
+			keys, ok := c.tags[tag]
+			if !ok {
+				keys = make(map[string]struct{})
+				c.tags[tag] = keys
+			}
+			keys[key] = struct{}{}
+		}
+		c.keys[key] = indexed{tags: e.Tags, expires: e.Expires}
+	}
+	if len(c.keys) >= c.sweepAt {
+		c.sweepLocked(time.Now())
+		if c.sweepAt = 2 * len(c.keys); c.sweepAt < minSweep {
+			c.sweepAt = minSweep
+		}
+	}
+	c.lock.Unlock()
+	return nil
+}
+
+// Delete removes entry of key
+func (c *Cache) Delete(key string) error {
+	c.untag(key)
+	return c.store.Delete(key)
+}
+
+// untag removes key from tag index
+func (c *Cache) untag(key string) {
+	c.lock.Lock()
+	c.untagLocked(key)
+	c.lock.Unlock()
+}
+
+func (c *Cache) untagLocked(key string) {
+	ix, ok := c.keys[key]
+	if !ok {
+		return
+	}
+	delete(c.keys, key)
+	for _, tag := range ix.tags {
+		if keys, ok := c.tags[tag]; ok {
+			delete(keys, key)
+			if len(keys) == 0 {
+				delete(c.tags, tag)
+			}
+		}
+	}
+}
+
+// sweepLocked removes keys expired at now from tag index
+func (c *Cache) sweepLocked(now time.Time) {
+	for key, ix := range c.keys {
+		if !ix.expires.IsZero() && now.After(ix.expires) {
+			c.untagLocked(key)
+		}
+	}
+}
+
+// Invalidate removes all entries tagged with any of tags
+// the tag index lives in memory, entries persisted by a previous
+// process are not reachable by tag and expire by ttl
+func (c *Cache) Invalidate(tags ...string) {
+	c.lock.Lock()
+	var keys []string
+	for _, tag := range tags {
+		for key := range c.tags[tag] {
+			keys = append(keys, key)
+		}
+	}
+	for _, key := range keys {
+		c.untagLocked(key)
+	}
+	c.lock.Unlock()
+	for _, key := range keys {
+		c.store.Delete(key)
+	}
+}
+
+// Lead elects one caller per key to build the response.
+// The leader gets leader == true and must call Done.
+// Other callers block until Done and get the leader's entry,
+// entry is nil if the leader response was not cacheable.
+func (c *Cache) Lead(key string) (e *Entry, leader bool) {
+	c.flightLock.Lock()
+	if f, ok := c.flights[key]; ok {
+		c.flightLock.Unlock()
+		f.wg.Wait()
+		return f.entry, false
+	}
+	f := &flight{}
+	f.wg.Add(1)
+	c.flights[key] = f
+	c.flightLock.Unlock()
+	return nil, true
+}
+
+// Done publishes leader entry for key to waiting callers
+// entry is stored if not nil
+func (c *Cache) Done(key string, e *Entry) {
+	if e != nil {
+		c.Set(key, e)
+	}
+	c.Share(key, e)
+}
+
+// Share publishes leader entry for key to waiting callers without storing it,
+// e.g. when leader stored it under another key
+func (c *Cache) Share(key string, e *Entry) {
+	c.flightLock.Lock()
+	f, ok := c.flights[key]
+	delete(c.flights, key)
+	c.flightLock.Unlock()
+	if ok {
+		f.entry = e
+		f.wg.Done()
+	}
+}
+
+type tagsKey struct{}
+
+// Tag adds tags to response of current request
+// tags are used with Cache.Invalidate
+func Tag(ctx *context.Context, tags ...string) {
+	var all []string
+	if v, ok := ctx.Input.GetData(tagsKey{}).([]string); ok {
+		all = v
+	}
+	ctx.Input.SetData(tagsKey{}, append(all, tags...))
+}
+
+// Tags returns tags added to response of current request
+func Tags(ctx *context.Context) []string {
+	v, _ := ctx.Input.GetData(tagsKey{}).([]string)
+	return v
+}
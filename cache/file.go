@@ -0,0 +1,77 @@
+package cache
+
+import (
+	"crypto/sha1"
+	"encoding/gob"
+	"encoding/hex"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+)
+
+// FileStore keeps entries as gob files in a directory
+// suitable for large bodies which should not stay in memory
+// files are sharded into subdirectories by key hash
+type FileStore struct {
+	dir string
+}
+
+// NewFileStore returns FileStore saving entries under dir
+func NewFileStore(dir string) (*FileStore, error) {
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return nil, err
+	}
+	return &FileStore{dir: dir}, nil
+}
+
+func (f *FileStore) path(key string) string {
+	sum := sha1.Sum([]byte(key))
+	name := hex.EncodeToString(sum[:])
+	return filepath.Join(f.dir, name[:2], name)
+}
+
+// Get returns entry of key
+func (f *FileStore) Get(key string) (*Entry, bool) {
+	file, err := os.Open(f.path(key))
+	if err != nil {
+		return nil, false
+	}
+	defer file.Close()
+	e := &Entry{}
+	if err = gob.NewDecoder(file).Decode(e); err != nil {
+		return nil, false
+	}
+	return e, true
+}
+
+// Set stores entry with key
+// entry is written to temp file then renamed so readers never see partial entry
+func (f *FileStore) Set(key string, e *Entry) error {
+	path := f.path(key)
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return err
+	}
+	tmp, err := ioutil.TempFile(filepath.Dir(path), ".tmp-")
+	if err != nil {
+		return err
+	}
+	if err = gob.NewEncoder(tmp).Encode(e); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err = tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+// Delete removes entry of key
+func (f *FileStore) Delete(key string) error {
+	err := os.Remove(f.path(key))
+	if os.IsNotExist(err) {
+		return nil
+	}
+	return err
+}
@@ -0,0 +1,54 @@
+package cache
+
+import (
+	lru "github.com/hashicorp/golang-lru"
+)
+
+// MemoryStore keeps entries in memory
+// least recently used entries are evicted when size is reached
+type MemoryStore struct {
+	lru     *lru.Cache
+	onEvict func(key string)
+}
+
+// NewMemoryStore returns MemoryStore holding at most size entries
+func NewMemoryStore(size int) *MemoryStore {
+	// avoid lru cache error
+	if size < 1 {
+		size = 1
+	}
+	m := &MemoryStore{}
+	m.lru, _ = lru.NewWithEvict(size, func(key, value interface{}) {
+		if m.onEvict != nil {
+			m.onEvict(key.(string))
+		}
+	})
+	return m
+}
+
+// OnEvict sets fn called with keys of removed entries, implements Evictor
+// call it before store is used
+func (m *MemoryStore) OnEvict(fn func(key string)) {
+	m.onEvict = fn
+}
+
+// Get returns entry of key
+func (m *MemoryStore) Get(key string) (*Entry, bool) {
+	v, ok := m.lru.Get(key)
+	if !ok {
+		return nil, false
+	}
+	return v.(*Entry), true
+}
+
+// Set stores entry with key
+func (m *MemoryStore) Set(key string, e *Entry) error {
+	m.lru.Add(key, e)
+	return nil
+}
+
+// Delete removes entry of key
+func (m *MemoryStore) Delete(key string) error {
+	m.lru.Remove(key)
+	return nil
+}
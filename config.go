@@ -7,6 +7,7 @@ import (
 	"runtime"
 	"errors"
 
+	"github.com/mellowarex/gon/cache"
 	"github.com/mellowarex/gon/logs"
 	"github.com/mellowarex/gon/session"
 	"github.com/mellowarex/gon/utils"
@@ -32,17 +33,21 @@ type Config struct {
 	MaxUploadSize       		int64
 	// EnableErrorsShow    bool
 	// EnableErrorsRender  bool
-	RecoverPanic						bool
+	RecoverPanic						bool // recover panics of requests with RecoverFunc, needed by Controller.Abort
 	RecoverFunc			func(*context.Context, *Config)			
 
 	DirectoryIndex						bool
 	StaticDir									map[string]string
 	StaticExtensionsToGzip		[]string
-	StaticCacheFileSize				int
+	StaticCacheFileSize				int // max bytes of static file kept in memory, 100KB if not set
 	StaticCacheFileNum				int
+	ResponseCacheNum					int // max entries of default in memory response cache
 	TemplateLeft							string
 	TemplateRight							string
 	ViewsPath									string
+	// TemplateReloadInterval milliseconds between scans of view paths for changed
+	// templates in development, 0 uses 1000 and negative turns reloading off
+	TemplateReloadInterval		int
 
 	EnvConfig
 }
@@ -56,6 +61,8 @@ var (
 	AppPath string
 	// GlobalSessions is instance for session manager
 	GlobalSessions *session.Manager
+	// ResponseCache stores responses of routes with cache policy
+	ResponseCache *cache.Cache
 	// appConfigPath is the path to application config
 	appConfigPath string
 	// envConfigPath is the path to environment config
@@ -118,6 +125,7 @@ func initGConfig() *Config {
 		EnableGzip:         false,
 		MaxMemory:          1 << 26, // 64MB
 		MaxUploadSize:      1 << 30, // 1GB
+		ResponseCacheNum:   1024,
 	}
 
 	return conf
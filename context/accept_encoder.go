@@ -9,7 +9,6 @@ import (
 	"sync"
 	"io"
 	"strconv"
-	"os"
 	"bytes"
 )
 
@@ -29,7 +28,7 @@ var (
 	noneCompressEncoder = acceptEncoder{"", nil, nil, nil}
 	gzipCompressEncoder = acceptEncoder{
 		name:                    "gzip",
-		levelEncode:             func(level int) resetWriter { wr, _ := gzip.NewWriterLevel(nil, level); return wr },
+		levelEncode:             func(level int) ResetWriter { wr, _ := gzip.NewWriterLevel(nil, level); return wr },
 		customCompressLevelPool: &sync.Pool{New: func() interface{} { wr, _ := gzip.NewWriterLevel(nil, gzipCompressLevel); return wr }},
 		bestCompressionPool:     &sync.Pool{New: func() interface{} { wr, _ := gzip.NewWriterLevel(nil, flate.BestCompression); return wr }},
 	}
@@ -40,7 +39,7 @@ var (
 	//the "deflate" compression mechanism described in RFC 1951 [29].
 	deflateCompressEncoder = acceptEncoder{
 		name:                    "deflate",
-		levelEncode:             func(level int) resetWriter { wr, _ := zlib.NewWriterLevel(nil, level); return wr },
+		levelEncode:             func(level int) ResetWriter { wr, _ := zlib.NewWriterLevel(nil, level); return wr },
 		customCompressLevelPool: &sync.Pool{New: func() interface{} { wr, _ := zlib.NewWriterLevel(nil, gzipCompressLevel); return wr }},
 		bestCompressionPool:     &sync.Pool{New: func() interface{} { wr, _ := zlib.NewWriterLevel(nil, flate.BestCompression); return wr }},
 	}
@@ -53,8 +52,61 @@ var (
 		"*":        gzipCompressEncoder, // * means any compress will accept,we prefer gzip
 		"identity": noneCompressEncoder, // identity means none-compress
 	}
+	encoderLock sync.RWMutex
 )
 
+// EncoderFunc returns a compress writer for the given compress level.
+// the writer is Reset to the destination before every use
+type EncoderFunc func(level int) ResetWriter
+
+// RegisterEncoder makes a compress encoder available by the provided
+// Accept-Encoding token, e.g. "br" or "zstd".
+// gon ships no brotli or zstd implementation; register one from a third party package:
+//
+//	context.RegisterEncoder("br", func(level int) context.ResetWriter {
+//		return brotli.NewWriterLevel(nil, level)
+//	})
+//
+// If factory is nil or name is one of "*" and "identity", it panics.
+func RegisterEncoder(name string, factory EncoderFunc) {
+	if factory == nil {
+		panic("context: RegisterEncoder provided is nil")
+	}
+	name = strings.ToLower(name)
+	if name == "*" || name == "identity" {
+		panic("context: RegisterEncoder can't override " + name)
+	}
+	encoderLock.Lock()
+	defer encoderLock.Unlock()
+	encoderMap[name] = acceptEncoder{
+		name:                    name,
+		levelEncode:             factory,
+		customCompressLevelPool: &sync.Pool{New: func() interface{} { return factory(gzipCompressLevel) }},
+		bestCompressionPool:     &sync.Pool{New: func() interface{} { return factory(flate.BestCompression) }},
+	}
+}
+
+// Encoders returns names of all registered compress encoders
+func Encoders() []string {
+	encoderLock.RLock()
+	defer encoderLock.RUnlock()
+	names := make([]string, 0, len(encoderMap))
+	for name, cf := range encoderMap {
+		if name == "*" || cf.name == "" {
+			continue
+		}
+		names = append(names, name)
+	}
+	return names
+}
+
+func getEncoder(name string) (acceptEncoder, bool) {
+	encoderLock.RLock()
+	defer encoderLock.RUnlock()
+	cf, ok := encoderMap[name]
+	return cf, ok
+}
+
 // InitGzip init the gzipcompress
 func InitGzip(minLength, compressLevel int, methods []string) {
 	if minLength >= 0 {
@@ -71,7 +123,8 @@ func InitGzip(minLength, compressLevel int, methods []string) {
 	}
 }
 
-type resetWriter interface{
+// ResetWriter is a compress writer which can be reused for another destination
+type ResetWriter interface {
 	io.Writer
 	Reset(w io.Writer)
 }
@@ -84,7 +137,7 @@ func (this blankResetWriter) Reset(w io.Writer) {}
 
 type acceptEncoder struct {
 	name  									 string
-	levelEncode  						 func(int) resetWriter
+	levelEncode  						 func(int) ResetWriter
 	customCompressLevelPool *sync.Pool
 	bestCompressionPool  		 *sync.Pool
 }
@@ -103,17 +156,17 @@ func ParseEncoding(r *http.Request) string {
 	return ""
 }
 
-func (this acceptEncoder) encode(wr io.Writer, level int) resetWriter {
+func (this acceptEncoder) encode(wr io.Writer, level int) ResetWriter {
 	if this.customCompressLevelPool == nil || this.bestCompressionPool == nil{
 		return blankResetWriter{wr}
 	}
 
-	var rwr resetWriter
+	var rwr ResetWriter
 	switch level {
 	case flate.BestSpeed:
-		rwr =  this.customCompressLevelPool.Get().(resetWriter)
+		rwr =  this.customCompressLevelPool.Get().(ResetWriter)
 	case flate.BestCompression:
-		rwr = this.bestCompressionPool.Get().(resetWriter)
+		rwr = this.bestCompressionPool.Get().(ResetWriter)
 	default:
 		rwr = this.levelEncode(level)
 	}
@@ -121,7 +174,7 @@ func (this acceptEncoder) encode(wr io.Writer, level int) resetWriter {
 	return rwr
 }
 
-func (this acceptEncoder) put(wr resetWriter, level int) {
+func (this acceptEncoder) put(wr ResetWriter, level int) {
 	if this.customCompressLevelPool == nil || this.bestCompressionPool == nil {
 		return
 	}
@@ -151,36 +204,75 @@ func parseEncoding(r *http.Request) string {
 	}
 
 	var lastQ q
-	for _, v := range strings.Split(acceptEncoding, ",") {
-		v = strings.TrimSpace(v)
-		if v == "" {
+	for _, v := range parseQValues(acceptEncoding) {
+		cf, ok := getEncoder(v.name)
+		if !ok {
 			continue
 		}
-		vs := strings.Split(v, ";")
-		var cf acceptEncoder
-		var ok bool
-		if cf, ok = encoderMap[vs[0]]; !ok {
-			continue
-		}
-		if len(vs) == 1 {
-			return cf.name
+		if v.value > lastQ.value {
+			lastQ = q{cf.name, v.value}
 		}
-		if len(vs) == 2 {
-			f, _ := strconv.ParseFloat(strings.Replace(vs[1], "q=", "", -1), 64)
-			if f == 0 {
-				continue
+	}
+	return lastQ.name
+}
+
+// NegotiateEncoding returns the offer with the highest q-value
+// in the request Accept-Encoding header
+// offers earlier in the list win ties
+// if none of the offers is acceptable, return empty string
+func NegotiateEncoding(r *http.Request, offers ...string) string {
+	if r == nil {
+		return ""
+	}
+	accepted := parseQValues(r.Header.Get("Accept-Encoding"))
+	var lastQ q
+	for _, offer := range offers {
+		f, ok := -1.0, false
+		for _, v := range accepted {
+			if v.name == offer {
+				f, ok = v.value, true
+				break
 			}
-			if f > lastQ.value {
-				lastQ = q{cf.name, f}
+			if v.name == "*" {
+				f = v.value
 			}
 		}
+		if !ok && f < 0 {
+			continue
+		}
+		if f > lastQ.value {
+			lastQ = q{offer, f}
+		}
 	}
 	return lastQ.name
 }
 
+// parseQValues splits header value such as "gzip;q=0.8, br"
+// into names with q-value, q defaults to 1
+func parseQValues(header string) []q {
+	var qs []q
+	for _, v := range strings.Split(header, ",") {
+		v = strings.TrimSpace(v)
+		if v == "" {
+			continue
+		}
+		vs := strings.Split(v, ";")
+		item := q{strings.ToLower(strings.TrimSpace(vs[0])), 1}
+		for _, param := range vs[1:] {
+			param = strings.TrimSpace(param)
+			if strings.HasPrefix(param, "q=") {
+				item.value, _ = strconv.ParseFloat(param[2:], 64)
+			}
+		}
+		qs = append(qs, item)
+	}
+	return qs
+}
+
 
 // WriteFile reads from file and writes to writer by the specific encoding(gzip/deflate)
-func WriteFile(encoding string, writer io.Writer, file *os.File) (bool, string, error) {
+// file may be any reader e.g. a file of fs.FS
+func WriteFile(encoding string, writer io.Writer, file io.Reader) (bool, string, error) {
 	return writeLevel(encoding, writer, file, flate.BestCompression)
 }
 
@@ -196,11 +288,11 @@ func WriteBody(encoding string, writer io.Writer, content []byte) (bool, string,
 // writeLevel reads from reader,writes to writer by specific encoding and compress level
 // the compress level is defined by deflate package
 func writeLevel(encoding string, writer io.Writer, reader io.Reader, level int) (bool, string, error) {
-	var outputWriter resetWriter
+	var outputWriter ResetWriter
 	var err error
 	var ce = noneCompressEncoder
 
-	if cf, ok := encoderMap[encoding]; ok {
+	if cf, ok := getEncoder(encoding); ok {
 		ce = cf
 	}
 	encoding = ce.name
@@ -9,8 +9,6 @@ import (
 	"time"
 	"fmt"
 	"strings"
-
-	"github.com/mellowarex/gon/utils"
 )
 
 type Context struct {
@@ -18,7 +16,7 @@ type Context struct {
 	Output  			*GonOutput
 	Request 				*http.Request
 	ResponseWriter	*Response
-	_xsrfToken			string
+	_xsrfToken			string // xsrf secret of request, see XSRFToken
 }
 
 // NewContext returns empty Input & Output
@@ -43,19 +41,6 @@ func (this *Context) Reset(w http.ResponseWriter, r *http.Request) {
 	this._xsrfToken = ""
 }
 
-// XSRFToken creates and returns xsrf token string
-func (this *Context) XSRFToken(key string, expire int64) string {
-	if this._xsrfToken == "" {
-		token, ok := this.GetSecureCookie(key, "_xsrf")
-		if !ok {
-			token = string(utils.RandomCreateBytes(32))
-			this.SetSecureCookie(key, "_xsrf", token, expire, "", "")
-		}
-		this._xsrfToken = token
-	}
-	return this._xsrfToken
-}
-
 // SetSecureCookie for response
 func (this *Context) SetSecureCookie(Secret, name, value string, others ...interface{}) {
 	vs := base64.URLEncoding.EncodeToString([]byte(value))
@@ -92,28 +77,6 @@ func (this *Context) GetSecureCookie(Secret, key string) (string, bool) {
 	return string(res), true
 }
 
-// CheckXSRFCookie checks if the XSRF token in this request is valid or not
-// The token can be provided in the request header in the form "X-Xsrftoken" or "X-CsrfToken"
-// or in form field value named as "_xsrf".
-func (this *Context) CheckXSRFCookie() bool {
-	token := this.Input.Query("_xsrf")
-	if token == "" {
-		token = this.Request.Header.Get("X-Xsrftoken")
-	}
-	if token == "" {
-		token = this.Request.Header.Get("X-Csrftoken")
-	}
-	if token == "" {
-		this.Abort(422, "422")
-		return false
-	}
-	if this._xsrfToken != token {
-		this.Abort(417, "417")
-		return false
-	}
-	return true
-}
-
 // WriteString writes string to response body
 func (this *Context) WriteString(content string) {
 	this.ResponseWriter.Write([]byte(content))
@@ -33,7 +33,7 @@ var (
 // - url params & params body
 type GonInput struct {
 	Context *Context
-	Cookie 	*session.Cookie
+	Cookie 	session.Store
 	Params	map[string]string
 	pnames	[]string
 	pvalues	[]string
@@ -0,0 +1,179 @@
+package context
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+
+	yaml "gopkg.in/yaml.v2"
+)
+
+// ErrNotAcceptable is returned when no registered renderer
+// matches the request Accept header
+var ErrNotAcceptable = errors.New("no acceptable representation")
+
+// Renderer serializes data into a media type representation
+type Renderer func(data interface{}) ([]byte, error)
+
+type mediaRenderer struct {
+	mime        string
+	contentType string
+	render      Renderer
+}
+
+var (
+	// renderers in order of preference, first one is used when client accepts anything
+	renderers = []mediaRenderer{
+		{ApplicationJSON, "application/json; charset=utf-8", json.Marshal},
+		{ApplicationXML, "application/xml; charset=utf-8", xml.Marshal},
+		{TextXML, "text/xml; charset=utf-8", xml.Marshal},
+		{ApplicationYAML, "application/x-yaml; charset=utf-8", yaml.Marshal},
+	}
+	renderersLock sync.RWMutex
+)
+
+// RegisterRenderer makes renderer available for media type mime
+// e.g. text/csv, application/msgpack or application/x-protobuf.
+// Registering an existing mime replaces its renderer.
+func RegisterRenderer(mime string, fn Renderer) {
+	if fn == nil {
+		panic("context: RegisterRenderer provided is nil")
+	}
+	mime = strings.ToLower(mime)
+	renderersLock.Lock()
+	defer renderersLock.Unlock()
+	for i, mr := range renderers {
+		if mr.mime == mime {
+			renderers[i].render = fn
+			return
+		}
+	}
+	renderers = append(renderers, mediaRenderer{mime, mime, fn})
+}
+
+// Renderers returns registered media types in order of preference
+func Renderers() []string {
+	renderersLock.RLock()
+	defer renderersLock.RUnlock()
+	mimes := make([]string, 0, len(renderers))
+	for _, mr := range renderers {
+		mimes = append(mimes, mr.mime)
+	}
+	return mimes
+}
+
+func getRenderer(mime string) (mediaRenderer, bool) {
+	renderersLock.RLock()
+	defer renderersLock.RUnlock()
+	for _, mr := range renderers {
+		if mr.mime == mime {
+			return mr, true
+		}
+	}
+	return mediaRenderer{}, false
+}
+
+// mediaRange is one element of Accept header: type/subtype;q=
+type mediaRange struct {
+	typ     string
+	subtype string
+	q       float64
+}
+
+// parseAccept splits Accept header such as
+// "text/html, application/*;q=0.8, */*;q=0.1" into media ranges
+func parseAccept(header string) []mediaRange {
+	var ranges []mediaRange
+	for _, v := range strings.Split(header, ",") {
+		v = strings.TrimSpace(v)
+		if v == "" {
+			continue
+		}
+		vs := strings.Split(v, ";")
+		mr := mediaRange{q: 1}
+		mt := strings.ToLower(strings.TrimSpace(vs[0]))
+		if mt == "*" {
+			mt = "*/*"
+		}
+		i := strings.Index(mt, "/")
+		if i < 0 {
+			continue
+		}
+		mr.typ, mr.subtype = mt[:i], mt[i+1:]
+		for _, param := range vs[1:] {
+			param = strings.TrimSpace(param)
+			if strings.HasPrefix(param, "q=") {
+				mr.q, _ = strconv.ParseFloat(param[2:], 64)
+			}
+		}
+		ranges = append(ranges, mr)
+	}
+	return ranges
+}
+
+// NegotiateContentType returns the offer best matching request Accept header.
+// The most specific media range matching an offer gives its q-value,
+// offers earlier in the list win ties.
+// If Accept is missing, the first offer is returned.
+// If no offer is acceptable, return empty string.
+func NegotiateContentType(r *http.Request, offers ...string) string {
+	if len(offers) == 0 {
+		return ""
+	}
+	accept := r.Header.Get("Accept")
+	if accept == "" {
+		return offers[0]
+	}
+	ranges := parseAccept(accept)
+	best, bestQ := "", 0.0
+	for _, offer := range offers {
+		offer = strings.ToLower(offer)
+		i := strings.Index(offer, "/")
+		if i < 0 {
+			continue
+		}
+		typ, subtype := offer[:i], offer[i+1:]
+		q, specificity := 0.0, -1
+		for _, mr := range ranges {
+			s := -1
+			switch {
+			case mr.typ == typ && mr.subtype == subtype:
+				s = 2
+			case mr.typ == typ && mr.subtype == "*":
+				s = 1
+			case mr.typ == "*" && mr.subtype == "*":
+				s = 0
+			}
+			if s > specificity {
+				q, specificity = mr.q, s
+			}
+		}
+		if q > bestQ {
+			best, bestQ = offer, q
+		}
+	}
+	return best
+}
+
+// Respond writes data in the representation negotiated from
+// request Accept header among registered renderers.
+// If none is acceptable, return ErrNotAcceptable without writing.
+func (output *GonOutput) Respond(data interface{}) error {
+	mime := NegotiateContentType(output.Context.Request, Renderers()...)
+	output.Context.ResponseWriter.Header().Add("Vary", "Accept")
+	if mime == "" {
+		return ErrNotAcceptable
+	}
+	mr, _ := getRenderer(mime)
+	content, err := mr.render(data)
+	if err != nil {
+		http.Error(output.Context.ResponseWriter, err.Error(), http.StatusInternalServerError)
+		return err
+	}
+	output.Header("Content-Type", mr.contentType)
+	return output.Body(content)
+}
@@ -13,9 +13,9 @@ import (
 	"html/template"
 	"os"
 	"path/filepath"
-	"net/url"
 	"net/http"
 	"mime"
+	"hash/fnv"
 
 	yaml "gopkg.in/yaml.v2"
 )
@@ -57,10 +57,15 @@ func (this *GonOutput) Header(key, val string) {
 // if EnableGzip, compress content string
 // then sent out response body directly
 func (this *GonOutput) Body(content []byte) error {
+	// answer conditional request with 304 when validators are set
+	if (this.Status == 0 || this.Status == http.StatusOK) && this.NotModified() {
+		return nil
+	}
 	var encoding string
 	var buf = &bytes.Buffer{}
 	if this.EnableGzip {
 		encoding = ParseEncoding(this.Context.Request)
+		this.Context.ResponseWriter.Header().Add("Vary", "Accept-Encoding")
 	}
 	if b, n, _ := WriteBody(encoding, buf, content); b {
 		this.Header("Content-Encoding", n)
@@ -252,9 +257,9 @@ func (output *GonOutput) YAML(data interface{}) error {
 }
 
 // ServeFormatted serves YAML, XML or JSON, depending on the value of the Accept header
+// JSON is served when none of them is acceptable
 func (output *GonOutput) ServeFormatted(data interface{}, hasEncode ...bool) error {
-	accept := output.Context.Input.Header("Accept")
-	switch accept {
+	switch NegotiateContentType(output.Context.Request, ApplicationJSON, ApplicationXML, TextXML, ApplicationYAML) {
 	case ApplicationYAML:
 		return output.YAML(data)
 	case ApplicationXML, TextXML:
@@ -266,12 +271,33 @@ func (output *GonOutput) ServeFormatted(data interface{}, hasEncode ...bool) err
 
 // Download forces response for download file.
 // Prepares the download response header automatically.
-func (output *GonOutput) Download(file string, filename ...string) error{
+// Range and If-Range requests are served so downloads can be resumed.
+func (output *GonOutput) Download(file string, filename ...string) error {
+	return output.downloadFile(file, false, filename...)
+}
+
+// DownloadInline serves file to be displayed in the browser
+// with the given filename used when the user saves it.
+func (output *GonOutput) DownloadInline(file string, filename ...string) error {
+	return output.downloadFile(file, true, filename...)
+}
+
+func (output *GonOutput) downloadFile(file string, inline bool, filename ...string) error {
 	// check get file error, file not found or other error.
-	if _, err := os.Stat(file); err != nil {
+	fi, err := os.Stat(file)
+	if err != nil || fi.IsDir() {
+		http.ServeFile(output.Context.ResponseWriter, output.Context.Request, file)
+		if err == nil {
+			err = errors.New(file + " is a directory")
+		}
+		return err
+	}
+	f, err := os.Open(file)
+	if err != nil {
 		http.ServeFile(output.Context.ResponseWriter, output.Context.Request, file)
 		return err
 	}
+	defer f.Close()
 
 	var fName string
 	if len(filename) > 0 && filename[0] != "" {
@@ -279,29 +305,84 @@ func (output *GonOutput) Download(file string, filename ...string) error{
 	} else {
 		fName = filepath.Base(file)
 	}
-	// https://tools.ietf.org/html/rfc6266#section-4.3
-	fn := url.PathEscape(fName)
-	if fName == fn {
-		fn = "filename=" + fn
+	return output.DownloadContent(f, fName, fi.ModTime(), inline)
+}
+
+// DownloadContent serves content such as generated reports or blobs as download.
+// Range, If-Range and multipart byteranges requests are answered from content.
+// modtime is used for Last-Modified and If-Range, zero time disables both.
+// If inline, the browser is asked to display content instead of saving it.
+func (output *GonOutput) DownloadContent(content io.ReadSeeker, filename string, modtime time.Time, inline bool) error {
+	disposition := "attachment"
+	if inline {
+		disposition = "inline"
+		if ctype := mime.TypeByExtension(filepath.Ext(filename)); ctype != "" {
+			output.Header("Content-Type", ctype)
+		}
 	} else {
+		output.Header("Content-Description", "File Transfer")
+		output.Header("Content-Type", "application/octet-stream")
+		output.Header("Content-Transfer-Encoding", "binary")
+	}
+	output.Header("Content-Disposition", ContentDisposition(disposition, filename))
+	output.Header("Expires", "0")
+	output.Header("Cache-Control", "must-revalidate")
+	output.Header("Pragma", "public")
+	http.ServeContent(output.Context.ResponseWriter, output.Context.Request, filename, modtime, content)
+	return nil
+}
+
+// ContentDisposition returns Content-Disposition header value
+// for disposition type "attachment" or "inline" and filename.
+// Non-ASCII filename is sent as ASCII fallback in "filename"
+// and UTF-8 encoded in "filename*".
+// https://tools.ietf.org/html/rfc6266#section-4.3
+func ContentDisposition(disposition, filename string) string {
+	if filename == "" {
+		return disposition
+	}
+	var fallback strings.Builder
+	ascii := true
+	for _, r := range filename {
+		switch {
+		case r == '"' || r == '\\':
+			fallback.WriteByte('_')
+		case r < 0x20 || r > 0x7e:
+			fallback.WriteByte('_')
+			ascii = false
+		default:
+			fallback.WriteRune(r)
+		}
+	}
+	v := disposition + "; filename=\"" + fallback.String() + "\""
+	if !ascii {
 		/**
 		  The parameters "filename" and "filename*" differ only in that
 		  "filename*" uses the encoding defined in [RFC5987], allowing the use
 		  of characters not present in the ISO-8859-1 character set
 		  ([ISO-8859-1]).
 		*/
-		fn = "filename=" + fName + "; filename*=utf-8''" + fn
+		v += "; filename*=UTF-8''" + encodeExtValue(filename)
 	}
-	output.Header("Content-Disposition", "attachment; "+fn)
-	output.Header("Content-Description", "File Transfer")
-	output.Header("Content-Type", "application/octet-stream")
-	output.Header("Content-Transfer-Encoding", "binary")
-	output.Header("Expires", "0")
-	output.Header("Cache-Control", "must-revalidate")
-	output.Header("Pragma", "public")
-	http.ServeFile(output.Context.ResponseWriter, output.Context.Request, file)
+	return v
+}
 
-	return nil
+// encodeExtValue percent-encodes s except RFC 5987 attr-char
+func encodeExtValue(s string) string {
+	const hex = "0123456789ABCDEF"
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if 'a' <= c && c <= 'z' || 'A' <= c && c <= 'Z' || '0' <= c && c <= '9' ||
+			strings.IndexByte("!#$&+-.^_`|~", c) >= 0 {
+			b.WriteByte(c)
+			continue
+		}
+		b.WriteByte('%')
+		b.WriteByte(hex[c>>4])
+		b.WriteByte(hex[c&15])
+	}
+	return b.String()
 }
 
 // ContentType sets the content type from ext string
@@ -328,6 +409,98 @@ func (this *GonOutput) IsCachable() bool {
 	return this.Status >= 200 && this.Status < 300 || this.Status == 304
 }
 
+// ETag sets response ETag header
+// tag is quoted if not already, weak marks it as weak validator W/"tag"
+func (this *GonOutput) ETag(tag string, weak ...bool) {
+	if !strings.HasPrefix(tag, "W/") && !strings.HasPrefix(tag, "\"") {
+		tag = "\"" + tag + "\""
+	}
+	if len(weak) > 0 && weak[0] && !strings.HasPrefix(tag, "W/") {
+		tag = "W/" + tag
+	}
+	this.Header("ETag", tag)
+}
+
+// LastModified sets response Last-Modified header
+func (this *GonOutput) LastModified(t time.Time) {
+	if t.IsZero() {
+		return
+	}
+	this.Header("Last-Modified", t.UTC().Format(http.TimeFormat))
+}
+
+// CacheControl sets response Cache-Control header
+// CacheControl("public", "max-age=3600")
+func (this *GonOutput) CacheControl(directives ...string) {
+	if len(directives) == 0 {
+		return
+	}
+	this.Header("Cache-Control", strings.Join(directives, ", "))
+}
+
+// WeakETag returns weak ETag computed from content bytes
+func WeakETag(content []byte) string {
+	h := fnv.New64a()
+	h.Write(content)
+	return fmt.Sprintf("W/\"%x-%x\"", len(content), h.Sum64())
+}
+
+// NotModified evaluates request If-None-Match and If-Modified-Since
+// against response ETag and Last-Modified headers.
+// If the client copy is fresh it writes 304 without body and returns true.
+// Only GET and HEAD requests are evaluated.
+func (this *GonOutput) NotModified() bool {
+	if !this.Context.Input.IsGet() && !this.Context.Input.IsHead() {
+		return false
+	}
+	header := this.Context.ResponseWriter.Header()
+	etag, modified := header.Get("ETag"), header.Get("Last-Modified")
+	if etag == "" && modified == "" {
+		return false
+	}
+	if !isFresh(this.Context.Request, etag, modified) {
+		return false
+	}
+	// 304 must not carry representation metadata for body
+	header.Del("Content-Type")
+	header.Del("Content-Length")
+	header.Del("Content-Encoding")
+	this.Status = 0
+	this.Context.ResponseWriter.WriteHeader(http.StatusNotModified)
+	return true
+}
+
+// isFresh reports whether request validators match etag or modified
+// If-None-Match takes precedence over If-Modified-Since
+// https://tools.ietf.org/html/rfc7232#section-6
+func isFresh(r *http.Request, etag, modified string) bool {
+	if inm := r.Header.Get("If-None-Match"); inm != "" {
+		if etag == "" {
+			return false
+		}
+		for _, v := range strings.Split(inm, ",") {
+			v = strings.TrimSpace(v)
+			if v == "*" || strings.TrimPrefix(v, "W/") == strings.TrimPrefix(etag, "W/") {
+				return true
+			}
+		}
+		return false
+	}
+	ims := r.Header.Get("If-Modified-Since")
+	if ims == "" || modified == "" {
+		return false
+	}
+	t, err := http.ParseTime(ims)
+	if err != nil {
+		return false
+	}
+	lm, err := http.ParseTime(modified)
+	if err != nil {
+		return false
+	}
+	return !lm.Truncate(time.Second).After(t)
+}
+
 // IsEmpty returns boolean if this request is empty
 // HTTP 201，204 and 304 means empty
 func (this *GonOutput) IsEmpty() bool {
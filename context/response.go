@@ -15,18 +15,39 @@ type Response struct {
 	Started bool 					// determine if response was already written
 	Status  int  					// HTTP status code
 	Elapsed time.Duration
+	beforeWrite []func()		// run once before header is written
 }
 
 func (this *Response) reset(rw http.ResponseWriter) {
 	this.ResponseWriter = rw
 	this.Status = 0
 	this.Started = false
+	this.beforeWrite = nil
+}
+
+// BeforeWrite registers fn to run once before response header is written,
+// fn may still change headers e.g. set cookies.
+func (this *Response) BeforeWrite(fn func()) {
+	this.beforeWrite = append(this.beforeWrite, fn)
+}
+
+// Commit runs pending BeforeWrite funcs
+// it is called for handlers which return without writing response
+func (this *Response) Commit() {
+	fns := this.beforeWrite
+	this.beforeWrite = nil
+	for _, fn := range fns {
+		fn()
+	}
 }
 
 // Write writes the data to the connection as part of an HTTP reply,
 // and sets `started` to true.
 // started means the response was set.
 func (this *Response) Write(p []byte) (int, error) {
+	if !this.Started {
+		this.Commit()
+	}
 	this.Started = true
 	return this.ResponseWriter.Write(p)
 }
@@ -38,6 +59,7 @@ func (this *Response) WriteHeader(code int) {
 		//prevent multiple response.WriteHeader calls
 		return
 	}
+	this.Commit()
 	this.Status = code
 	this.Started = true
 	this.ResponseWriter.WriteHeader(code)
@@ -55,6 +77,9 @@ func (this *Response) Hijack() (net.Conn, *bufio.ReadWriter, error) {
 // Flush http.Flusher
 // flush buffered data to client
 func (this *Response) Flush() {
+	if !this.Started {
+		this.Commit()
+	}
 	if f, ok := this.ResponseWriter.(http.Flusher); ok {
 		f.Flush()
 	}
@@ -0,0 +1,157 @@
+package context
+
+import (
+	context2 "context"
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"errors"
+	"io"
+	"net/url"
+	"strings"
+
+	"github.com/mellowarex/gon/utils"
+)
+
+// xsrf errors returned by CheckXSRF
+var (
+	ErrXSRFMissing = errors.New("xsrf token missing")
+	ErrXSRFInvalid = errors.New("xsrf token invalid")
+	ErrXSRFOrigin  = errors.New("xsrf origin not allowed")
+)
+
+// xsrfName name of xsrf cookie, session key and form field
+const xsrfName = "_xsrf"
+
+// XSRFToken returns xsrf token of request
+// the secret is kept in session when sessions are on (synchronizer token),
+// otherwise in cookie signed by key (double submit cookie).
+// It is created by the first call, so only requests rendering a token
+// write session or cookie. Each call returns the secret masked with new random bytes,
+// so it never shows same in responses (BREACH).
+// Responses of requests calling it are user specific and not stored in
+// response cache, see XSRFTokenUsed
+func (this *Context) XSRFToken(key string, expire int64) string {
+	if this._xsrfToken == "" {
+		this._xsrfToken = this.xsrfSecret(key)
+		if this._xsrfToken == "" {
+			this._xsrfToken = string(utils.RandomCreateBytes(32))
+			if store := this.Input.Cookie; store != nil {
+				store.Set(context2.Background(), xsrfName, this._xsrfToken, this.Request, this.ResponseWriter)
+			} else {
+				this.SetSecureCookie(key, xsrfName, this._xsrfToken, expire, "/", "", this.Input.IsSecure(), true)
+			}
+		}
+	}
+	return maskXSRF(this._xsrfToken)
+}
+
+// XSRFTokenUsed reports whether XSRFToken was called in request
+func (this *Context) XSRFTokenUsed() bool {
+	return this._xsrfToken != ""
+}
+
+// xsrfSecret returns xsrf secret saved by previous request
+func (this *Context) xsrfSecret(key string) string {
+	if store := this.Input.Cookie; store != nil {
+		secret, _ := store.Get(context2.Background(), xsrfName).(string)
+		return secret
+	}
+	secret, _ := this.GetSecureCookie(key, xsrfName)
+	return secret
+}
+
+// maskXSRF returns base64 of random mask and secret xor mask
+func maskXSRF(secret string) string {
+	b := make([]byte, 2*len(secret))
+	if _, err := io.ReadFull(rand.Reader, b[:len(secret)]); err != nil {
+		panic(err)
+	}
+	for i := 0; i < len(secret); i++ {
+		b[len(secret)+i] = b[i] ^ secret[i]
+	}
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+// unmaskXSRF returns secret of token masked by maskXSRF
+func unmaskXSRF(token string) string {
+	b, err := base64.RawURLEncoding.DecodeString(token)
+	if err != nil || len(b) == 0 || len(b)%2 != 0 {
+		return ""
+	}
+	n := len(b) / 2
+	for i := 0; i < n; i++ {
+		b[n+i] ^= b[i]
+	}
+	return string(b[n:])
+}
+
+// CheckXSRF checks xsrf token of request against secret of key
+// the token is read from form field "_xsrf" or header X-Xsrftoken or X-Csrftoken.
+// Unless checkOrigin is false the Origin header, or Referer without it,
+// must be of request host or one of trusted origins e.g. https://app.example.com
+// or *.example.com
+func (this *Context) CheckXSRF(key string, checkOrigin bool, trusted ...string) error {
+	if checkOrigin && !this.xsrfOriginAllowed(trusted) {
+		return ErrXSRFOrigin
+	}
+	token := this.Input.Query(xsrfName)
+	if token == "" {
+		token = this.Request.Header.Get("X-Xsrftoken")
+	}
+	if token == "" {
+		token = this.Request.Header.Get("X-Csrftoken")
+	}
+	if token == "" {
+		return ErrXSRFMissing
+	}
+	secret := this._xsrfToken
+	if secret == "" {
+		secret = this.xsrfSecret(key)
+	}
+	if secret == "" || subtle.ConstantTimeCompare([]byte(unmaskXSRF(token)), []byte(secret)) != 1 {
+		return ErrXSRFInvalid
+	}
+	return nil
+}
+
+// CheckXSRFCookie checks if the XSRF token in this request is valid or not
+// The token can be provided in the request header in the form "X-Xsrftoken" or "X-CsrfToken"
+// or in form field value named as "_xsrf". XSRFToken must be called before.
+func (this *Context) CheckXSRFCookie() bool {
+	return this.CheckXSRF("", false) == nil
+}
+
+// xsrfOriginAllowed reports whether Origin or Referer of request is allowed
+// requests without both are left to token check
+func (this *Context) xsrfOriginAllowed(trusted []string) bool {
+	origin := this.Request.Header.Get("Origin")
+	if origin == "" {
+		origin = this.Request.Header.Get("Referer")
+	}
+	if origin == "" {
+		return true
+	}
+	u, err := url.Parse(origin)
+	if err != nil || u.Host == "" {
+		return false
+	}
+	if strings.EqualFold(u.Host, this.Request.Host) {
+		return true
+	}
+	for _, t := range trusted {
+		switch {
+		case strings.Contains(t, "://"):
+			if strings.EqualFold(u.Scheme+"://"+u.Host, strings.TrimRight(t, "/")) {
+				return true
+			}
+		case strings.HasPrefix(t, "*."):
+			if strings.HasSuffix(strings.ToLower(u.Host), strings.ToLower(t[1:])) {
+				return true
+			}
+		case strings.EqualFold(u.Host, t):
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,98 @@
+package ctrl
+
+import (
+	"fmt"
+	"html/template"
+	"io"
+
+	"github.com/mellowarex/gon"
+	"github.com/mellowarex/gon/auth"
+)
+
+// CurrentUser returns user of request authenticated by route guard
+// or by auth.Default strategies, nil for anonymous requests
+func (c *Controller) CurrentUser() interface{} {
+	user := auth.User(c.Ctx)
+	// remember-me login may have regenerated session
+	c.Cookie = c.Ctx.Input.Cookie
+	return user
+}
+
+// Remember logs user in with remember-me cookie valid for maxAge seconds,
+// WebConfig.Auth.RememberMaxAge if not given. Used after Login:
+//
+//	if err := this.Login(user.ID); err == nil && this.GetString("remember") != "" {
+//		this.Remember(user.ID)
+//	}
+func (c *Controller) Remember(userID interface{}, maxAge ...int64) bool {
+	age := gon.GConfig.WebConfig.Auth.RememberMaxAge
+	if len(maxAge) > 0 {
+		age = maxAge[0]
+	}
+	return auth.Remember(c.Ctx, fmt.Sprint(userID), age)
+}
+
+// Can reports whether current user may perform action, on resource if given
+// see auth.Authz
+func (c *Controller) Can(action string, resource ...interface{}) bool {
+	return auth.Can(c.Ctx, action, resource...)
+}
+
+// HasRole reports whether current user has role
+func (c *Controller) HasRole(role string) bool {
+	return auth.HasRole(c.Ctx, role)
+}
+
+// Authorize aborts with 401 for anonymous users and with 403
+// when current user may not perform action, on resource if given
+//
+//	post := loadPost(this.Params["id"])
+//	this.Authorize("posts.edit", post)
+func (c *Controller) Authorize(action string, resource ...interface{}) {
+	if c.CurrentUser() == nil {
+		c.Abort("401")
+	}
+	if !c.Can(action, resource...) {
+		c.Abort("403")
+	}
+}
+
+// controllerKey key of Data holding controller rendering it,
+// template funcs taking $ find request of template through it
+type controllerKey struct{}
+
+// Can reports whether current user of request rendering template may perform action,
+// on resource if given. It takes root data $ of template, reports false outside requests:
+// {{if can $ "posts.edit" .Post}}<a href="/posts/{{.Post.ID}}/edit">Edit</a>{{end}}
+func Can(data interface{}, action string, resource ...interface{}) bool {
+	if c := dataController(data); c != nil {
+		return c.Can(action, resource...)
+	}
+	return false
+}
+
+// HasRole reports whether current user of request rendering template has role
+// it takes root data $ of template: {{if has_role $ "admin"}}
+func HasRole(data interface{}, role string) bool {
+	if c := dataController(data); c != nil {
+		return c.HasRole(role)
+	}
+	return false
+}
+
+// dataController returns controller of template data, nil if data is not its Data
+func dataController(data interface{}) *Controller {
+	if m, ok := data.(map[interface{}]interface{}); ok {
+		c, _ := m[controllerKey{}].(*Controller)
+		return c
+	}
+	return nil
+}
+
+// executeTemplate executes template name of t with data
+func executeTemplate(t *template.Template, wr io.Writer, name string, data interface{}) error {
+	if t.Lookup(name) != nil {
+		return t.ExecuteTemplate(wr, name, data)
+	}
+	return t.Execute(wr, data)
+}
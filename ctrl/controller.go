@@ -4,10 +4,12 @@ import (
 	"bytes"
 	context2 "context"
 	"crypto/rand"
-	"errors"
 	"fmt"
 	"golang.org/x/crypto/bcrypt"
 	"github.com/mellowarex/gon"
+	"github.com/mellowarex/gon/auth"
+	"github.com/mellowarex/gon/cache"
+	"github.com/mellowarex/gon/logs"
 	"github.com/mellowarex/gon/context"
 	"github.com/mellowarex/gon/session"
 	"html/template"
@@ -24,11 +26,12 @@ import (
 
 var (
 	// ErrAbort custom error user error induced error
-	ErrAbort = errors.New("user stop run")
+	ErrAbort = gon.ErrAbort
 	ErrorMaps = map[string]bool{
 		"401": false,
 		"402": false,
 		"403": false,
+		"406": false,
 		"413": false,
 		"422": false,
 		"417": false,
@@ -52,13 +55,14 @@ type Controller struct {
 
 	Data 								map[interface{}]interface{}
 	Params 							map[string]string
-	Flash  							map[string]string
+	Flash  							*FlashData
+	Lang   							string // locale of request, see Tr
 
 	ControllerName   		string
 	ActionName   				string
 
 	// session
-	Cookie   						*session.Cookie
+	Cookie   						session.Store
 
 	// template data
 	TplName 						string
@@ -78,16 +82,21 @@ type Controller struct {
 func (this *Controller) Init(ctx *context.Context, listen gon.Listen) {
 	this.Layout = ""
 	this.TplName = ""
+	this._xsrfToken = ""
 	this.Ctx = ctx
+	this.Cookie = ctx.Input.Cookie
 	this.Data = make(map[interface{}]interface{})
 	this.Params = ctx.Input.Params
-	this.Flash = make(map[string]string)
+	this.Flash = &FlashData{}
 	this.LayoutSections = make(map[string]string)
 	this.Writer = ctx.ResponseWriter
 	this.Request = ctx.Request
 	this.methodMapping = make(map[string]func())
 
 	this.Listen = listen
+	this.Lang = this.detectLang()
+	this.Data["Lang"] = this.Lang
+	this.Data[controllerKey{}] = this
 }
 
 func (this *Controller) BeforeAction() {}
@@ -158,6 +167,16 @@ func (this *Controller) Render() error {
 		return err
 	}
 
+	// validate rendered page with weak etag unless action set its own
+	if this.Ctx.Output.Status == 0 {
+		if this.Writer.Header().Get("ETag") == "" {
+			this.Ctx.Output.ETag(context.WeakETag(tpl))
+		}
+		if this.Ctx.Output.NotModified() {
+			return nil
+		}
+	}
+
 	var encoding string
 	var buf = &bytes.Buffer{}
 
@@ -173,6 +192,23 @@ func (this *Controller) Render() error {
 }
 
 func (this *Controller)RenderBytes() ([]byte, error){
+	// layout compiled with page and sections renders in single execution
+	if this.Layout != "" {
+		this.setTplName()
+		if t := this.layoutTemplate(); t != nil {
+			for sectionName, sectionTpl := range this.LayoutSections {
+				if sectionTpl == "" {
+					this.Data[sectionName] = ""
+				}
+			}
+			var buf bytes.Buffer
+			err := executeTemplate(t, &buf, this.Layout, this.Data)
+			if err != nil {
+				logs.Error("template execute err: %v", err)
+			}
+			return buf.Bytes(), err
+		}
+	}
 	buf, err := this.RenderTemplate()
 	// if the controller has set layout, then first get the tplName's content set the content to the layout
 	if err == nil && this.Layout != "" {
@@ -199,25 +235,6 @@ func (this *Controller)RenderBytes() ([]byte, error){
 	return buf.Bytes(), err
 }
 
-func (this *Controller) ReadFlashData() {
-	flash := this.Flash
-	if cookie, err := this.Ctx.Request.Cookie(gon.GConfig.WebConfig.FlashName); err == nil {
-		v, _ := url.QueryUnescape(cookie.Value)
-		vals := strings.Split(v, "\x00")
-		for _, v := range vals {
-			if len(v) > 0 {
-				kv := strings.Split(v, "\x23" + gon.GConfig.WebConfig.FlashSeparator + "\x23")
-				if len(kv) == 2 {
-					flash[kv[0]] = kv[1]
-				}
-			}
-		}
-		this.Ctx.SetCookie(gon.GConfig.WebConfig.FlashName, "", -1, "/")
-	}
-	this.Data["Flash"] = flash
-}
-
-
 func writeBody(encoding string, writer io.Writer, content []byte) (bool, string, error) {
 	_, err := writer.Write(content)
 	return false, "", err
@@ -226,12 +243,7 @@ func writeBody(encoding string, writer io.Writer, content []byte) (bool, string,
 // RenderTemplate returns bytes of rendered template string
 func (c *Controller) RenderTemplate() (bytes.Buffer, error) {
 	var buf bytes.Buffer
-	if c.TplName == "" {
-		if len(c.ControllerName) > 0 {
-			c.TplName = strings.ToLower(c.ControllerName) + "/"
-		}
-		c.TplName += strings.ToLower(c.ActionName) + ".tpl"
-	}
+	c.setTplName()
 	buildFiles := []string{c.TplName}
 	if c.Layout != "" {
 		buildFiles = append(buildFiles, c.Layout)
@@ -244,10 +256,24 @@ func (c *Controller) RenderTemplate() (bytes.Buffer, error) {
 			}
 		}
 	}
-	buildTemplate(c.viewPath(), buildFiles...)
+	// templates are compiled on start and rebuilt by WatchTemplates,
+	// development builds ones not seen yet e.g. added before watcher scanned
+	if gon.GConfig.EnvMode == gon.DEV && !templateBuilt(c.viewPath(), buildFiles...) {
+		buildTemplate(c.viewPath(), buildFiles...)
+	}
 	return buf, executeViewPathTemplate(&buf, c.TplName, c.viewPath(), c.Data)
 }
 
+// setTplName sets TplName to controller/action.tpl if empty
+func (c *Controller) setTplName() {
+	if c.TplName == "" {
+		if len(c.ControllerName) > 0 {
+			c.TplName = strings.ToLower(c.ControllerName) + "/"
+		}
+		c.TplName += strings.ToLower(c.ActionName) + ".tpl"
+	}
+}
+
 func (c *Controller) viewPath() string {
 	if c.ViewPath == "" {
 		return "views"
@@ -255,7 +281,9 @@ func (c *Controller) viewPath() string {
 	return c.ViewPath
 }
 
-// XSRFToken creates a CSRF token string and returns
+// XSRFToken returns xsrf token of request, its secret is saved on first call
+// so only actions rendering forms e.g. with XSRFField write session or cookie.
+// Responses rendering it are not stored in response cache, see Route.Cache
 func (c *Controller) XSRFToken() string {
 	if c._xsrfToken == "" {
 		expire := int64(gon.GConfig.WebConfig.XSRFExpire)
@@ -269,12 +297,15 @@ func (c *Controller) XSRFToken() string {
 
 // CheckXSRFCookie checks xsrf token in this request is valid or not.
 // the token can provided in request header "X-Xsrftoken" and "X-CsrfToken"
-// or in form field value named as "_xsrf".
+// or in form field value named as "_xsrf". Origin is checked as configured.
+// requests are checked before action when WebConfig.EnableXSRF is set,
+// controllers may check themselves by setting EnableXSRF
 func (c *Controller) CheckXSRFCookie() bool {
 	if !c.EnableXSRF {
 		return true
 	}
-	return c.Ctx.CheckXSRFCookie()
+	conf := gon.GConfig.WebConfig
+	return c.Ctx.CheckXSRF(conf.XSRFKey, !conf.XSRFDisableOriginCheck, conf.XSRFTrustedOrigins...) == nil
 }
 
 func (c *Controller) ControllerFunc(fn string) bool {
@@ -311,7 +342,7 @@ func (c *Controller) GetCookie(key string) string{
 	return c.Ctx.Input.GetCookie(key)
 }
 
-func (c *Controller) StartSession() *session.Cookie {
+func (c *Controller) StartSession() session.Store {
 	if c.Cookie == nil {
 		c.Cookie = c.Ctx.Input.Cookie
 	}
@@ -319,13 +350,13 @@ func (c *Controller) StartSession() *session.Cookie {
 }
 
 // SetSession puts value into session by name
+// changed session is saved before response is written
 func (c *Controller) SetSession(name string, value interface{}) error {
 	if c.Cookie == nil {
 		c.StartSession()
 	}
 
 	c.Cookie.Set(context2.Background(), name, value,c.Request, c.Writer)
-	c.Cookie.Save(context2.Background(),nil, c.Request, c.Writer)
 	return nil
 }
 
@@ -354,15 +385,18 @@ func (c *Controller) FlushSession() error {
 }
 
 // SessionRegenerateID regenerates session id for this session.
-// the session data have no changes.
+// the session data have no changes, values set before are kept.
 func (c *Controller) SessionRegenerateID() error {
 	// if c.Session == nil {
 	// 	c.Session.SessionRelease(context2.Background(), c.Ctx.ResponseWriter)
 	// }
-	var err error
-	c.Cookie, err = gon.GlobalSessions.SessionRegenerateID(c.Ctx.ResponseWriter, c.Ctx.Request)
-	c.Ctx.Input.Cookie = c.Cookie
-	return err
+	store, err := gon.GlobalSessions.SessionRegenerateID(c.Ctx.ResponseWriter, c.Ctx.Request, c.Ctx.Input.Cookie)
+	if err != nil {
+		return err
+	}
+	c.Cookie = store
+	c.Ctx.Input.Cookie = store
+	return nil
 }
 
 // DestroySession cleans session data and session cookie.
@@ -371,11 +405,34 @@ func (c *Controller) DestroySession() error {
 	if err != nil {
 		return err
 	}
+	c.Cookie = nil
 	c.Ctx.Input.Cookie = nil
 	gon.GlobalSessions.SessionDestroy(c.Ctx.ResponseWriter, c.Ctx.Request)
 	return nil
 }
 
+// Login regenerates session id and stores userID in session
+// new id on privilege change prevents session fixation
+func (c *Controller) Login(userID interface{}) error {
+	if err := c.SessionRegenerateID(); err != nil {
+		return err
+	}
+	auth.Clear(c.Ctx)
+	return c.SetSession(session.UserIDKey, userID)
+}
+
+// Logout destroys session of logged in user and forgets remember-me cookie
+func (c *Controller) Logout() error {
+	auth.Forget(c.Ctx)
+	auth.SetUser(c.Ctx, nil)
+	return c.DestroySession()
+}
+
+// UserID returns user id stored by Login, nil if not logged in
+func (c *Controller) UserID() interface{} {
+	return c.GetSession(session.UserIDKey)
+}
+
 // Input returns the input data map from POST or PUT request body & query string
 func (c *Controller) Input() (url.Values, error) {
 	if c.Ctx.Request.Form == nil {
@@ -668,89 +725,59 @@ func (this *Controller) RedirectTo(code int, url string) {
 	this.Ctx.Redirect(code, url)
 }
 
-// Success writes success message flash
+// Success adds success message to flash
 func (c *Controller) Success(msg string, args ...interface{}) {
-	//write success only once
-	if _,ok := c.Flash["success"]; ok {
-		return
-	}
-	if len(args) == 0 {
-		c.Flash["Success"] = msg
-	} else {
-		c.Flash["Success"] = fmt.Sprintf(msg, args...)
-	}
-
-	c.Store()
+	c.FlashMsg("Success", msg, args...)
 }
 
-// Notice writes notice message flash
+// Notice adds notice message to flash
 func (c *Controller) Notice(msg string, args ...interface{}) {
-	//write success only once
-	if _,ok := c.Flash["Notice"]; ok {
-		return
-	}
-	if len(args) == 0 {
-		c.Flash["Notice"] = msg
-	} else {
-		c.Flash["Notice"] = fmt.Sprintf(msg, args...)
-	}
-
-	c.Store()
+	c.FlashMsg("Notice", msg, args...)
 }
 
-// Warning writes warning messages to flash
+// Warning adds warning message to flash
 func (c *Controller) Warning(msg string, args ...interface{}) {
-	//write success only once
-	if _,ok := c.Flash["Warning"]; ok {
-		return
-	}
-	if len(args) == 0 {
-		c.Flash["Warning"] = msg
-	} else {
-		c.Flash["Warning"] = fmt.Sprintf(msg, args...)
-	}
-	c.Store()
+	c.FlashMsg("Warning", msg, args...)
 }
 
-// Error writes error messages to flash
+// Error adds error message to flash
 func (c *Controller) Error(msg string, args ...interface{}) {
-	//write success only once
-	if _,ok := c.Flash["Error"]; ok {
-		return
-	}
-	if len(args) == 0 {
-		c.Flash["Error"] = msg
-	} else {
-		c.Flash["Error"] = fmt.Sprintf(msg, args...)
-	}
-
-	c.Store()
+	c.FlashMsg("Error", msg, args...)
 }
 
-// Set custom message to flash
+// FlashMsg adds message of custom level to flash
+// a level may hold several messages
 func (c *Controller) FlashMsg(key, msg string, args ...interface{}) {
-	//write custom flash only once
-	if _,ok := c.Flash[key]; ok {
-		return
-	}
-	if len(args) == 0 {
-		c.Flash[key] = msg
-	} else {
-		c.Flash[key] = fmt.Sprintf(msg, args...)
+	if len(args) > 0 {
+		msg = fmt.Sprintf(msg, args...)
 	}
+	c.Flash.Add(key, msg)
+	c.Store()
+}
 
+// KeepFlash keeps flash messages read in this request for the next one
+func (c *Controller) KeepFlash() {
+	c.Flash.Keep()
 	c.Store()
 }
 
-// Store save flash data
-// data is encoded and saved in cookie
+// Store save flash data for next request
+// in session when sessions are on, otherwise in encrypted cookie
 func (c *Controller) Store() {
-	c.Data["Flash"] = c.Flash
-	var flashValue string
-	for key, value := range c.Flash {
-		flashValue += "\x00" + key + "\x23" + gon.GConfig.WebConfig.FlashSeparator + "\x23" + value + "\x00"
+	c.setFlashData()
+	if store := c.Ctx.Input.Cookie; store != nil {
+		if err := store.Set(context2.Background(), flashSessionKey, c.Flash.next, c.Request, c.Writer); err != nil {
+			logs.Error(err)
+		}
+		return
+	}
+	name := gon.GConfig.WebConfig.FlashName
+	value, err := sealFlash(name, c.Flash.next)
+	if err != nil {
+		logs.Error(err)
+		return
 	}
-	c.Ctx.SetCookie(gon.GConfig.WebConfig.FlashName, url.QueryEscape(flashValue), 0, "/")
+	c.setFlashCookie(value)
 }
 
 // SetData set the data depending on the accepted
@@ -766,6 +793,17 @@ func (c *Controller) SetData(data interface{}) {
 	}
 }
 
+// Respond sends data in the representation the client prefers
+// json, xml, yaml or any renderer added with context.RegisterRenderer.
+// If the client accepts none of them, it responds 406 and returns context.ErrNotAcceptable.
+func (c *Controller) Respond(data interface{}) error {
+	err := c.Ctx.Output.Respond(data)
+	if err == context.ErrNotAcceptable {
+		http.Error(c.Ctx.ResponseWriter, http.StatusText(http.StatusNotAcceptable), http.StatusNotAcceptable)
+	}
+	return err
+}
+
 // SendJSON sends a json response with encoding charset.
 func (c *Controller) SendJSON(encoding ...bool) error {
 	var (
@@ -790,6 +828,13 @@ func (c *Controller) SendYAML() error {
 	return c.Ctx.Output.YAML(c.Data["yaml"])
 }
 
+// DownloadContent sends content as download named fileName
+// ranges are supported so clients can resume the download
+// if inline, the browser displays content instead of saving it
+func (c *Controller) DownloadContent(content io.ReadSeeker, fileName string, modtime time.Time, inline bool) error {
+	return c.Ctx.Output.DownloadContent(content, fileName, modtime, inline)
+}
+
 // download file
 func (c *Controller) DownloadFile(file , fileName string) error {
 	return c.Ctx.Output.Download(file, fileName)
@@ -816,6 +861,12 @@ func (c *Controller) DownloadFile(file , fileName string) error {
 	// return c.Ctx.Output.Download()
 }
 
+// CacheTags tags the response for server side response cache
+// tagged responses are removed with gon.ResponseCache.Invalidate(tags...)
+func (c *Controller) CacheTags(tags ...string) {
+	cache.Tag(c.Ctx, tags...)
+}
+
 // StopRun makes panic of USERSTOPRUN error and go to recover function if defined.
 func (c *Controller) StopRun() {
 	panic(ErrAbort)
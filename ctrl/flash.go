@@ -0,0 +1,220 @@
+package ctrl
+
+import (
+	context2 "context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/gob"
+	"encoding/json"
+	"errors"
+	"html/template"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+
+	"github.com/mellowarex/gon"
+	"github.com/mellowarex/gon/logs"
+)
+
+// flashSessionKey session key holding flash messages
+const flashSessionKey = "_flash"
+
+func init() {
+	gob.Register([]FlashMessage{})
+}
+
+// FlashMessage flash message with its level e.g. Success, Error
+type FlashMessage struct {
+	Level string
+	Text  string
+}
+
+// FlashData flash messages of request
+// messages read from previous request are shown in this request,
+// messages added are shown in this and the next request.
+// Messages are kept in session when sessions are on,
+// otherwise in an encrypted cookie named by WebConfig.FlashName
+type FlashData struct {
+	current []FlashMessage // read from previous request
+	next    []FlashMessage // saved for next request
+}
+
+// Add adds message of level
+func (f *FlashData) Add(level, msg string) {
+	f.next = append(f.next, FlashMessage{Level: level, Text: msg})
+}
+
+// Keep keeps messages read from previous request for next request too
+func (f *FlashData) Keep() {
+	f.next = append(append([]FlashMessage{}, f.current...), f.next...)
+	f.current = nil
+}
+
+// Messages returns all messages in order, read then added
+func (f *FlashData) Messages() []FlashMessage {
+	return append(append([]FlashMessage{}, f.current...), f.next...)
+}
+
+// Get returns messages of level
+func (f *FlashData) Get(level string) []string {
+	var msgs []string
+	for _, m := range f.Messages() {
+		if m.Level == level {
+			msgs = append(msgs, m.Text)
+		}
+	}
+	return msgs
+}
+
+// Has reports whether there are messages of level
+func (f *FlashData) Has(level string) bool {
+	return len(f.Get(level)) > 0
+}
+
+// Map returns first message of each level
+// templates written for single message per level use it as .Flash
+func (f *FlashData) Map() map[string]string {
+	m := make(map[string]string)
+	for _, msg := range f.Messages() {
+		if _, ok := m[msg.Level]; !ok {
+			m[msg.Level] = msg.Text
+		}
+	}
+	return m
+}
+
+var (
+	flashAEAD     cipher.AEAD
+	flashAEADOnce sync.Once
+)
+
+// flashCipher returns AES-GCM keyed by sha256 of WebConfig.FlashKey
+// a random key is used when FlashKey is empty
+func flashCipher() cipher.AEAD {
+	flashAEADOnce.Do(func() {
+		key := gon.GConfig.WebConfig.FlashKey
+		if key == "" {
+			logs.Warn("flash: FlashKey is empty, using generated key; flash cookies will not survive restart")
+			b := make([]byte, 32)
+			io.ReadFull(rand.Reader, b)
+			key = string(b)
+		}
+		sum := sha256.Sum256([]byte(key))
+		block, _ := aes.NewCipher(sum[:])
+		flashAEAD, _ = cipher.NewGCM(block)
+	})
+	return flashAEAD
+}
+
+// sealFlash encrypts and signs msgs for flash cookie
+func sealFlash(name string, msgs []FlashMessage) (string, error) {
+	b, err := json.Marshal(msgs)
+	if err != nil {
+		return "", err
+	}
+	aead := flashCipher()
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(aead.Seal(nonce, nonce, b, []byte(name))), nil
+}
+
+// openFlash decrypts flash cookie value sealed by sealFlash
+func openFlash(name, value string) ([]FlashMessage, error) {
+	b, err := base64.RawURLEncoding.DecodeString(value)
+	if err != nil {
+		return nil, err
+	}
+	aead := flashCipher()
+	if len(b) < aead.NonceSize() {
+		return nil, errors.New("flash: invalid cookie")
+	}
+	b, err = aead.Open(nil, b[:aead.NonceSize()], b[aead.NonceSize():], []byte(name))
+	if err != nil {
+		return nil, errors.New("flash: invalid cookie")
+	}
+	var msgs []FlashMessage
+	err = json.Unmarshal(b, &msgs)
+	return msgs, err
+}
+
+// setFlashCookie writes flash cookie, empty value deletes it
+func (c *Controller) setFlashCookie(value string) {
+	cookie := &http.Cookie{
+		Name:     gon.GConfig.WebConfig.FlashName,
+		Value:    value,
+		Path:     "/",
+		HttpOnly: true,
+		Secure:   c.Ctx.Input.IsSecure(),
+		SameSite: http.SameSiteLaxMode,
+	}
+	if value == "" {
+		cookie.MaxAge = -1
+	}
+	http.SetCookie(c.Ctx.ResponseWriter, cookie)
+}
+
+// ReadFlashData reads flash messages saved by previous request
+// and removes them from session or cookie
+func (c *Controller) ReadFlashData() {
+	if store := c.Ctx.Input.Cookie; store != nil {
+		if msgs, ok := store.Get(context2.Background(), flashSessionKey).([]FlashMessage); ok {
+			c.Flash.current = msgs
+			store.Delete(context2.Background(), flashSessionKey, c.Request, c.Writer)
+		}
+	} else if cookie, err := c.Ctx.Request.Cookie(gon.GConfig.WebConfig.FlashName); err == nil {
+		if msgs, err := openFlash(cookie.Name, cookie.Value); err == nil {
+			c.Flash.current = msgs
+		}
+		c.setFlashCookie("")
+	}
+	c.setFlashData()
+}
+
+// setFlashData exposes flash to templates
+// .Flash holds first message per level, .Flashes all messages
+func (c *Controller) setFlashData() {
+	c.Data["Flash"] = c.Flash.Map()
+	c.Data["Flashes"] = c.Flash
+}
+
+// Flashes returns flash messages of f, of given levels if any
+func Flashes(f *FlashData, levels ...string) []FlashMessage {
+	if f == nil {
+		return nil
+	}
+	msgs := f.Messages()
+	if len(levels) == 0 {
+		return msgs
+	}
+	var filtered []FlashMessage
+	for _, m := range msgs {
+		for _, level := range levels {
+			if m.Level == level {
+				filtered = append(filtered, m)
+				break
+			}
+		}
+	}
+	return filtered
+}
+
+// RenderFlashes renders flash messages of f as html
+// each message is <div class="flash flash-{level}">{text}</div>
+// usage: {{render_flashes .Flashes}}
+func RenderFlashes(f *FlashData, levels ...string) template.HTML {
+	var b strings.Builder
+	for _, m := range Flashes(f, levels...) {
+		b.WriteString(`<div class="flash flash-`)
+		b.WriteString(template.HTMLEscapeString(strings.ToLower(m.Level)))
+		b.WriteString(`">`)
+		b.WriteString(template.HTMLEscapeString(m.Text))
+		b.WriteString("</div>\n")
+	}
+	return template.HTML(b.String())
+}
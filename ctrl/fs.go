@@ -1,17 +1,71 @@
 package ctrl
 
 import (
+	"io/fs"
 	"net/http"
 	"os"
 	"path/filepath"
+
+	"github.com/mellowarex/gon"
 )
 
+// FileSystem opens template files from disk
 type FileSystem struct {}
 
 func (d FileSystem) Open(name string) (http.File, error) {
 	return os.Open(name)
 }
 
+// fallbackFS opens name from first, then from second if first has not it
+type fallbackFS struct {
+	first  http.FileSystem
+	second http.FileSystem
+}
+
+func (f fallbackFS) Open(name string) (http.File, error) {
+	file, err := f.first.Open(name)
+	if err != nil && os.IsNotExist(err) {
+		return f.second.Open(name)
+	}
+	return file, err
+}
+
+// slashFS opens names of fs.FS with forward slashes
+// template paths are joined with filepath
+type slashFS struct {
+	fs http.FileSystem
+}
+
+func (f slashFS) Open(name string) (http.File, error) {
+	return f.fs.Open(filepath.ToSlash(name))
+}
+
+// SetTemplateFS sets file system templates are read from
+// and rebuilds templates of all view paths in development. Usage with embed:
+//
+//	//go:embed views
+//	var views embed.FS
+//
+//	ctrl.SetTemplateFS(views)
+//
+// view paths are looked up by their name in fsys e.g. "views/index.tpl".
+// In development templates on disk are read first so edits show without rebuilding,
+// otherwise fsys is read first and disk only for files fsys has not.
+func SetTemplateFS(fsys fs.FS) error {
+	embedded := slashFS{http.FS(fsys)}
+	if gon.GConfig.EnvMode != gon.DEV {
+		// compiled on start by CompileTemplates
+		gonTemplateFS = func() http.FileSystem {
+			return fallbackFS{first: embedded, second: FileSystem{}}
+		}
+		return nil
+	}
+	gonTemplateFS = func() http.FileSystem {
+		return fallbackFS{first: FileSystem{}, second: embedded}
+	}
+	return CompileTemplates()
+}
+
 // Walk walks the file tree rooted at root in filesystem, calling walkFn for each file or
 // directory in the tree, including root. All errors that arise visiting files
 // and directories are filtered by walkFn.
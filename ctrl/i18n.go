@@ -0,0 +1,88 @@
+package ctrl
+
+import (
+	context2 "context"
+	"net/http"
+	"time"
+
+	"github.com/mellowarex/gon"
+	"github.com/mellowarex/gon/i18n"
+)
+
+// detectLang returns locale of request
+// read from url prefix, cookie, session then Accept-Language.
+// Response varies on the request headers locale is read from, Cookie
+// when lang cookie or session is consulted and Accept-Language when locale
+// is negotiated, so ResponseCache and shared caches keep a response per locale
+func (c *Controller) detectLang() string {
+	conf := gon.GConfig.WebConfig.I18n
+	if !conf.I18nOn {
+		return i18n.Default.DefaultLang
+	}
+	// url prefix is part of cache key already
+	if lang, ok := c.Ctx.Input.GetData(i18n.DataKey).(string); ok {
+		if m := i18n.Default.Match(lang); m != "" {
+			return m
+		}
+	}
+	var candidates []string
+	store := c.Ctx.Input.Cookie
+	if conf.CookieName != "" {
+		if cookie, err := c.Ctx.Request.Cookie(conf.CookieName); err == nil {
+			candidates = append(candidates, cookie.Value)
+		}
+	}
+	if store != nil {
+		if lang, ok := store.Get(context2.Background(), i18n.SessionKey).(string); ok {
+			candidates = append(candidates, lang)
+		}
+	}
+	if conf.CookieName != "" || store != nil {
+		c.Ctx.ResponseWriter.Header().Add("Vary", "Cookie")
+	}
+	for _, lang := range candidates {
+		if m := i18n.Default.Match(lang); m != "" {
+			return m
+		}
+	}
+	c.Ctx.ResponseWriter.Header().Add("Vary", "Accept-Language")
+	return i18n.Default.Detect(c.Ctx.Request)
+}
+
+// Tr translates key to locale of request
+// usage: this.Tr("inbox.messages", count)
+func (c *Controller) Tr(key string, args ...interface{}) string {
+	return i18n.Default.Tr(c.Lang, key, args...)
+}
+
+// SetLang sets locale of request and keeps it for next requests
+// in cookie and in session if started. Unknown locales are ignored
+func (c *Controller) SetLang(lang string) bool {
+	lang = i18n.Default.Match(lang)
+	if lang == "" {
+		return false
+	}
+	c.Lang = lang
+	c.Data["Lang"] = lang
+	if name := gon.GConfig.WebConfig.I18n.CookieName; name != "" {
+		http.SetCookie(c.Ctx.ResponseWriter, &http.Cookie{
+			Name:     name,
+			Value:    lang,
+			Path:     "/",
+			MaxAge:   int((365 * 24 * time.Hour).Seconds()),
+			HttpOnly: true,
+			Secure:   c.Ctx.Input.IsSecure(),
+			SameSite: http.SameSiteLaxMode,
+		})
+	}
+	if store := c.Ctx.Input.Cookie; store != nil {
+		store.Set(context2.Background(), i18n.SessionKey, lang, c.Request, c.Writer)
+	}
+	return true
+}
+
+// I18n translates key to locale lang. Used by the template parser as "i18n":
+// {{i18n .Lang "home.title"}} or {{i18n .Lang "inbox.messages" .Count}}
+func I18n(lang, key string, args ...interface{}) string {
+	return i18n.Default.Tr(lang, key, args...)
+}
@@ -0,0 +1,31 @@
+package ctrl
+
+import (
+	"github.com/mellowarex/gon/jwt"
+)
+
+// IssueToken returns token of claims signed by jwt.Default
+// iss, aud, iat and exp are set from WebConfig.JWT unless claims have them
+//
+//	token, err := this.IssueToken(&jwt.Claims{Subject: user.ID})
+func (c *Controller) IssueToken(claims *jwt.Claims) (string, error) {
+	return jwt.Default.Issue(claims)
+}
+
+// JWKSController serves JSON Web Key Set of public keys of Keys,
+// of jwt.Default if nil, so clients can verify tokens
+//
+//	mux.Route("/.well-known/jwks.json", &ctrl.JWKSController{})
+type JWKSController struct {
+	Controller
+	Keys *jwt.KeySet
+}
+
+// Get serves JWKS document
+func (c *JWKSController) Get() {
+	keys := c.Keys
+	if keys == nil {
+		keys = jwt.Default.Keys
+	}
+	keys.ServeHTTP(c.Writer, c.Request)
+}
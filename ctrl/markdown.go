@@ -0,0 +1,195 @@
+package ctrl
+
+import (
+	"bytes"
+	"html/template"
+	"regexp"
+	"strings"
+)
+
+// MarkdownEngine template engine for Markdown views
+// the file is converted to HTML by Markdown, then parsed as html/template,
+// so template actions such as {{.Title}} and {{template "x.tpl" .}} work in it.
+// usage:
+//
+//	ctrl.AddTemplateEngine("md", ctrl.MarkdownEngine)
+func MarkdownEngine(root, path string, funcs template.FuncMap) (*template.Template, error) {
+	src, err := TemplateSource(root, path)
+	if err != nil {
+		return nil, err
+	}
+	html := string(Markdown(src))
+	t, err := template.New(path).Delims("{{", "}}").Funcs(funcs).Parse(html)
+	if err != nil {
+		return nil, err
+	}
+	for _, m := range tplIncludeRegex.FindAllStringSubmatch(html, -1) {
+		if t.Lookup(m[1]) != nil || !HasTemplateExt(m[1]) {
+			continue
+		}
+		if t, _, err = getTplDeep(root, gonTemplateFS(), m[1], path, t); err != nil {
+			return nil, err
+		}
+	}
+	return t, nil
+}
+
+var (
+	tplIncludeRegex = regexp.MustCompile(`{{[ ]*template[ ]+"([^"]+)"`)
+	mdActionRegex   = regexp.MustCompile(`{{.*?}}`)
+	mdHeadingRegex  = regexp.MustCompile(`^(#{1,6})[ \t]+(.*?)[ \t#]*$`)
+	mdRuleRegex     = regexp.MustCompile(`^ {0,3}(?:(?:-[ ]*){3,}|(?:\*[ ]*){3,}|(?:_[ ]*){3,})$`)
+	mdULRegex       = regexp.MustCompile(`^[ ]{0,3}[-*+][ \t]+(.*)$`)
+	mdOLRegex       = regexp.MustCompile(`^[ ]{0,3}\d+[.)][ \t]+(.*)$`)
+	mdCodeRegex     = regexp.MustCompile("`([^`]+)`")
+	mdImageRegex    = regexp.MustCompile(`!\[([^\]]*)\]\(([^)\s]+)\)`)
+	mdLinkRegex     = regexp.MustCompile(`\[([^\]]+)\]\(([^)\s]+)\)`)
+	mdStrongRegex   = regexp.MustCompile(`(\*\*|__)(\S(?:.*?\S)?)(\*\*|__)`)
+	mdEmRegex       = regexp.MustCompile(`(^|[^\w*])[*_](\S(?:.*?\S)?)[*_]($|[^\w*])`)
+)
+
+// Markdown converts Markdown src to HTML
+// it covers headings, paragraphs, emphasis, inline code, links, images,
+// lists, block quotes, fenced code blocks and rules. Template actions
+// {{...}} are kept as they are and lines holding only an action are not
+// wrapped in paragraphs.
+func Markdown(src []byte) []byte {
+	lines := strings.Split(strings.Replace(string(src), "\r\n", "\n", -1), "\n")
+	var out bytes.Buffer
+	var para, quote []string
+	list := ""
+	inCode := false
+
+	flushPara := func() {
+		if len(para) > 0 {
+			out.WriteString("<p>" + mdInline(strings.Join(para, "\n")) + "</p>\n")
+			para = nil
+		}
+	}
+	flushList := func() {
+		if list != "" {
+			out.WriteString("</" + list + ">\n")
+			list = ""
+		}
+	}
+	flushQuote := func() {
+		if len(quote) > 0 {
+			out.WriteString("<blockquote>\n")
+			out.Write(Markdown([]byte(strings.Join(quote, "\n"))))
+			out.WriteString("</blockquote>\n")
+			quote = nil
+		}
+	}
+	flush := func() {
+		flushPara()
+		flushList()
+		flushQuote()
+	}
+
+	for _, line := range lines {
+		trimmed := strings.TrimSpace(line)
+		if inCode {
+			if strings.HasPrefix(trimmed, "```") {
+				out.WriteString("</code></pre>\n")
+				inCode = false
+				continue
+			}
+			out.WriteString(mdEscape(line) + "\n")
+			continue
+		}
+		if strings.HasPrefix(trimmed, ">") {
+			flushPara()
+			flushList()
+			quote = append(quote, strings.TrimPrefix(strings.TrimPrefix(trimmed, ">"), " "))
+			continue
+		}
+		flushQuote()
+		switch {
+		case trimmed == "":
+			flushPara()
+			flushList()
+		case strings.HasPrefix(trimmed, "```"):
+			flush()
+			inCode = true
+			if lang := strings.TrimSpace(trimmed[3:]); lang != "" {
+				out.WriteString(`<pre><code class="language-` + template.HTMLEscapeString(lang) + `">`)
+			} else {
+				out.WriteString("<pre><code>")
+			}
+		case mdActionRegex.FindString(trimmed) == trimmed:
+			flush()
+			out.WriteString(trimmed + "\n")
+		case mdHeadingRegex.MatchString(line):
+			flush()
+			m := mdHeadingRegex.FindStringSubmatch(line)
+			level := string('0' + byte(len(m[1])))
+			out.WriteString("<h" + level + ">" + mdInline(m[2]) + "</h" + level + ">\n")
+		case mdRuleRegex.MatchString(line):
+			flush()
+			out.WriteString("<hr>\n")
+		case mdULRegex.MatchString(line), mdOLRegex.MatchString(line):
+			flushPara()
+			kind, m := "ul", mdULRegex.FindStringSubmatch(line)
+			if m == nil {
+				kind, m = "ol", mdOLRegex.FindStringSubmatch(line)
+			}
+			if list != kind {
+				flushList()
+				out.WriteString("<" + kind + ">\n")
+				list = kind
+			}
+			out.WriteString("<li>" + mdInline(m[1]) + "</li>\n")
+		default:
+			if list != "" && (strings.HasPrefix(line, " ") || strings.HasPrefix(line, "\t")) {
+				// continuation of list item is appended to it
+				out.Truncate(out.Len() - len("</li>\n"))
+				out.WriteString(" " + mdInline(trimmed) + "</li>\n")
+				continue
+			}
+			flushList()
+			para = append(para, trimmed)
+		}
+	}
+	if inCode {
+		out.WriteString("</code></pre>\n")
+	}
+	flush()
+	return out.Bytes()
+}
+
+// mdInline converts inline Markdown of s, keeping template actions
+func mdInline(s string) string {
+	var out strings.Builder
+	last := 0
+	for _, loc := range mdActionRegex.FindAllStringIndex(s, -1) {
+		out.WriteString(mdSpan(s[last:loc[0]]))
+		out.WriteString(s[loc[0]:loc[1]])
+		last = loc[1]
+	}
+	out.WriteString(mdSpan(s[last:]))
+	return out.String()
+}
+
+// mdSpan converts inline Markdown of text holding no template action
+func mdSpan(s string) string {
+	// code spans are escaped and kept from other rules
+	var codes []string
+	s = mdCodeRegex.ReplaceAllStringFunc(s, func(m string) string {
+		codes = append(codes, "<code>"+mdEscape(m[1:len(m)-1])+"</code>")
+		return "\x00" + string(rune('0'+len(codes)-1)) + "\x00"
+	})
+	s = mdEscape(s)
+	s = mdImageRegex.ReplaceAllString(s, `<img src="$2" alt="$1">`)
+	s = mdLinkRegex.ReplaceAllString(s, `<a href="$2">$1</a>`)
+	s = mdStrongRegex.ReplaceAllString(s, "<strong>$2</strong>")
+	s = mdEmRegex.ReplaceAllString(s, "$1<em>$2</em>$3")
+	for i, c := range codes {
+		s = strings.Replace(s, "\x00"+string(rune('0'+i))+"\x00", c, 1)
+	}
+	return s
+}
+
+// mdEscape escapes html special characters of s
+func mdEscape(s string) string {
+	return strings.NewReplacer("&", "&amp;", "<", "&lt;", ">", "&gt;", `"`, "&quot;").Replace(s)
+}
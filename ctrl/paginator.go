@@ -0,0 +1,177 @@
+package ctrl
+
+import (
+	"html/template"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+// PageParam query parameter holding page number
+const PageParam = "p"
+
+// Paginator pages of a list of Total items shown PerPage per page
+// usage in controller:
+//
+//	p := this.Paginator(20, total)
+//	posts := loadPosts(p.Offset(), p.PerPage)
+//
+// and in template:
+//
+//	{{paginate .Paginator}}
+type Paginator struct {
+	Request *http.Request
+	PerPage int
+	Total   int64
+	// MaxLinks number of page links shown around current page, default 9
+	MaxLinks int
+
+	page int
+}
+
+// NewPaginator returns paginator of total items for request r
+// current page is read from query parameter "p"
+func NewPaginator(r *http.Request, perPage int, total int64) *Paginator {
+	if perPage <= 0 {
+		perPage = 10
+	}
+	p := &Paginator{Request: r, PerPage: perPage, Total: total, MaxLinks: 9}
+	page, _ := strconv.Atoi(r.URL.Query().Get(PageParam))
+	p.SetPage(page)
+	return p
+}
+
+// Paginator returns paginator of total items for request
+// from GetInt("p") and sets it to Data["Paginator"]
+func (c *Controller) Paginator(perPage int, total int64) *Paginator {
+	p := NewPaginator(c.Ctx.Request, perPage, total)
+	if page, err := c.GetInt(PageParam, 1); err == nil {
+		p.SetPage(page)
+	}
+	c.Data["Paginator"] = p
+	return p
+}
+
+// SetPage sets current page, kept in 1..Pages()
+func (p *Paginator) SetPage(page int) {
+	if page > p.Pages() {
+		page = p.Pages()
+	}
+	if page < 1 {
+		page = 1
+	}
+	p.page = page
+}
+
+// Page returns current page
+func (p *Paginator) Page() int {
+	return p.page
+}
+
+// Pages returns number of pages, at least 1
+func (p *Paginator) Pages() int {
+	if p.Total <= 0 {
+		return 1
+	}
+	return int((p.Total + int64(p.PerPage) - 1) / int64(p.PerPage))
+}
+
+// Offset returns index of first item of current page
+func (p *Paginator) Offset() int {
+	return (p.page - 1) * p.PerPage
+}
+
+// HasPages reports whether there is more than one page
+func (p *Paginator) HasPages() bool {
+	return p.Pages() > 1
+}
+
+// HasPrev reports whether there is page before current one
+func (p *Paginator) HasPrev() bool {
+	return p.page > 1
+}
+
+// HasNext reports whether there is page after current one
+func (p *Paginator) HasNext() bool {
+	return p.page < p.Pages()
+}
+
+// IsActive reports whether page is current page
+func (p *Paginator) IsActive(page int) bool {
+	return p.page == page
+}
+
+// PageLink returns url of request for page
+// other query parameters are kept
+func (p *Paginator) PageLink(page int) string {
+	u := *p.Request.URL
+	values := u.Query()
+	if page <= 1 {
+		values.Del(PageParam)
+	} else {
+		values.Set(PageParam, strconv.Itoa(page))
+	}
+	u.RawQuery = values.Encode()
+	return (&url.URL{Path: u.Path, RawQuery: u.RawQuery}).String()
+}
+
+// PrevLink returns url of previous page
+func (p *Paginator) PrevLink() string {
+	return p.PageLink(p.page - 1)
+}
+
+// NextLink returns url of next page
+func (p *Paginator) NextLink() string {
+	return p.PageLink(p.page + 1)
+}
+
+// Nums returns pages to link, at most MaxLinks around current page
+func (p *Paginator) Nums() []int {
+	pages, max := p.Pages(), p.MaxLinks
+	if max <= 0 || max > pages {
+		max = pages
+	}
+	start := p.page - max/2
+	if start < 1 {
+		start = 1
+	}
+	if start+max-1 > pages {
+		start = pages - max + 1
+	}
+	nums := make([]int, max)
+	for i := range nums {
+		nums[i] = start + i
+	}
+	return nums
+}
+
+// Paginate renders links of paginator p as html
+// nothing is rendered for single page. Used by the template parser as "paginate"
+func Paginate(p *Paginator) template.HTML {
+	if p == nil || !p.HasPages() {
+		return ""
+	}
+	var b strings.Builder
+	link := func(page int, text, class string) {
+		b.WriteString(`<li class="page-item` + class + `"><a class="page-link" href="`)
+		b.WriteString(template.HTMLEscapeString(p.PageLink(page)))
+		b.WriteString(`">` + text + "</a></li>")
+	}
+	b.WriteString(`<nav class="pagination"><ul>`)
+	if p.HasPrev() {
+		link(p.page-1, "&laquo;", "")
+	}
+	for _, n := range p.Nums() {
+		class := ""
+		if p.IsActive(n) {
+			class = " active"
+		}
+		link(n, strconv.Itoa(n), class)
+	}
+	if p.HasNext() {
+		link(p.page+1, "&raquo;", "")
+	}
+	b.WriteString("</ul></nav>")
+	return template.HTML(b.String())
+}
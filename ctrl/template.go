@@ -10,9 +10,12 @@ import (
 	"net/http"
 	"os"
 	"path/filepath"
+	"reflect"
 	"regexp"
 	"strings"
 	"sync"
+
+	"github.com/mellowarex/gon"
 )
 
 var (
@@ -42,8 +45,15 @@ func init() {
 	gonTplFuncMap["renderform"] = RenderForm
 	gonTplFuncMap["assets_js"] = AssetsJs
 	gonTplFuncMap["assets_css"] = AssetsCSS
-	// gonTplFuncMap["config"] = GetConfig
+	gonTplFuncMap["config"] = GetConfig
+	gonTplFuncMap["asset"] = Asset
+	gonTplFuncMap["paginate"] = Paginate
+	gonTplFuncMap["i18n"] = I18n
 	gonTplFuncMap["map_get"] = MapGet
+	gonTplFuncMap["flashes"] = Flashes
+	gonTplFuncMap["render_flashes"] = RenderFlashes
+	gonTplFuncMap["can"] = Can
+	gonTplFuncMap["has_role"] = HasRole
 
 	// Comparisons
 	gonTplFuncMap["eq"] = eq // ==
@@ -55,12 +65,79 @@ func init() {
 
 	gonTplFuncMap["urlfor"] = URLFor // build a URL to match a Controller and it's method
 	
+	// templates are compiled on start, see CompileTemplates
 	gonViewPathTemplates["views"] = make(map[string]*template.Template)
-	buildTemplate("views")
 }
 
+// AddFuncMap adds function fn to templates as name
+// call it before templates are built i.e. before gon.Run, e.g. in init
+func AddFuncMap(name string, fn interface{}) error {
+	if name == "" || reflect.TypeOf(fn) == nil || reflect.TypeOf(fn).Kind() != reflect.Func {
+		return errors.New("template func needs name and func")
+	}
+	templatesLock.Lock()
+	gonTplFuncMap[name] = fn
+	templatesLock.Unlock()
+	return nil
+}
+
+// templatePreProcessor builds template of file path under view root
+// engines read source with TemplateSource and may transform it before parsing
 type templatePreProcessor func(root, path string, funcs template.FuncMap) (*template.Template, error)
 
+// AddTemplateExt adds template file extension to build
+// files with it are found in view paths and parsed as html/template
+func AddTemplateExt(ext string) {
+	ext = strings.TrimPrefix(ext, ".")
+	templatesLock.Lock()
+	defer templatesLock.Unlock()
+	for _, v := range gonTemplateExt {
+		if v == ext {
+			return
+		}
+	}
+	gonTemplateExt = append(gonTemplateExt, ext)
+}
+
+// AddTemplateEngine registers template engine fn for files with extension ext
+// the extension is added to built extensions. Files of engine are used as
+// TplName, Layout, LayoutSections and in {{template "file.ext"}} like others.
+// usage:
+//
+//	ctrl.AddTemplateEngine("md", ctrl.MarkdownEngine)
+func AddTemplateEngine(ext string, fn templatePreProcessor) error {
+	ext = strings.TrimPrefix(ext, ".")
+	if ext == "" || fn == nil {
+		return errors.New("template engine needs extension and func")
+	}
+	AddTemplateExt(ext)
+	templatesLock.Lock()
+	gonTemplateEngines[ext] = fn
+	templatesLock.Unlock()
+	return nil
+}
+
+// templateEngine returns engine registered for extension of file
+func templateEngine(file string) (templatePreProcessor, bool) {
+	ext := filepath.Ext(file)
+	if len(ext) == 0 {
+		return nil, false
+	}
+	fn, ok := gonTemplateEngines[ext[1:]]
+	return fn, ok
+}
+
+// TemplateSource returns content of template file path under view root
+// read from template file system
+func TemplateSource(root, path string) ([]byte, error) {
+	f, err := gonTemplateFS().Open(filepath.Join(root, path))
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return io.ReadAll(f)
+}
+
 func defaultFSFunc() http.FileSystem {
 	return FileSystem{}
 }
@@ -136,11 +213,8 @@ func buildTemplate(dir string, files ...string) error {
 		for _, file := range v {
 			if buildAllFiles || inSlice(file, files) {
 				templatesLock.Lock()
-				ext := filepath.Ext(file)
 				var t *template.Template
-				if len(ext) == 0 {
-					t, err = getTemplate(self.root, fs, file, v...)
-				} else if fn, ok := gonTemplateEngines[ext[1:]]; ok {
+				if fn, ok := templateEngine(file); ok {
 					t, err = fn(self.root, file, gonTplFuncMap)
 				} else {
 					t, err = getTemplate(self.root, fs, file, v...)
@@ -152,6 +226,7 @@ func buildTemplate(dir string, files ...string) error {
 				}
 				gonTemplates[file] = t
 				templatesLock.Unlock()
+				clearLayouts(dir)
 			}
 		}
 	}
@@ -194,6 +269,26 @@ func getTplDeep(root string, fs http.FileSystem, file string, parent string, t *
 		rParent = file
 		fileAbsPath = filepath.Join(root, file)
 	}
+	// file of engine is built by it and added to t
+	if fn, ok := templateEngine(file); ok {
+		et, err := fn(root, rParent, gonTplFuncMap)
+		if err != nil {
+			return nil, [][]string{}, err
+		}
+		for _, tpl := range et.Templates() {
+			if tpl.Tree == nil || t.Lookup(tpl.Name()) != nil {
+				continue
+			}
+			name := tpl.Name()
+			if name == rParent {
+				name = file
+			}
+			if t, err = t.AddParseTree(name, tpl.Tree); err != nil {
+				return nil, [][]string{}, err
+			}
+		}
+		return t, [][]string{}, nil
+	}
 	f, err := fs.Open(fileAbsPath)
 	if err != nil {
 		panic("can't find template file:" + file)
@@ -203,7 +298,12 @@ func getTplDeep(root string, fs http.FileSystem, file string, parent string, t *
 	if err != nil {
 		return nil, [][]string{}, err
 	}
-	t, err = t.New(file).Parse(string(data))
+	var parentSubs [][]string
+	if loc := tplExtendsRegex.FindSubmatchIndex(data); loc != nil {
+		t, parentSubs, err = getTplExtends(root, fs, file, rParent, string(data), loc, t)
+	} else {
+		t, err = t.New(file).Parse(string(data))
+	}
 	if err != nil {
 		return nil, [][]string{}, err
 	}
@@ -224,7 +324,7 @@ func getTplDeep(root string, fs http.FileSystem, file string, parent string, t *
 			}
 		}
 	}
-	return t, allSub, nil
+	return t, append(allSub, parentSubs...), nil
 }
 
 func _getTemplate(t0 *template.Template, root string, fs http.FileSystem, subMods [][]string, others ...string) (t *template.Template, err error) {
@@ -291,19 +391,15 @@ func _getTemplate(t0 *template.Template, root string, fs http.FileSystem, subMod
 // ExecuteViewPathTemplate applies the template with name and from specific viewPath to the specified data object,
 // writing the output to wr.
 // A template will be executed safely in parallel.
+// templates only change after start in development, so only it locks
 func executeViewPathTemplate(wr io.Writer, name string, viewPath string, data interface{}) error {
-	if true {//server.GConfig.EnvMode == server.DEV {
+	if gon.GConfig.EnvMode == gon.DEV {
 		templatesLock.RLock()
 		defer templatesLock.RUnlock()
 	}
 	if gonTemplates, ok := gonViewPathTemplates[viewPath]; ok {
 		if t, ok := gonTemplates[name]; ok {
-			var err error
-			if t.Lookup(name) != nil {
-				err = t.ExecuteTemplate(wr, name, data)
-			} else {
-				err = t.Execute(wr, data)
-			}
+			err := executeTemplate(t, wr, name, data)
 			if err != nil {
 				log.Println("template Execute err:", err)
 			}
@@ -0,0 +1,255 @@
+package ctrl
+
+import (
+	"errors"
+	"fmt"
+	"html/template"
+	"log"
+	"net/http"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"sync"
+	"text/template/parse"
+)
+
+// Template inheritance
+//
+// a page extends a base template by naming it in its first action
+// and overriding blocks the base declares with {{block}} or {{define}}:
+//
+//	layouts/main.tpl:
+//	<html><head><title>{{block "title" .}}Site{{end}}</title></head>
+//	<body>{{block "content" .}}{{end}}</body></html>
+//
+//	home/index.tpl:
+//	{{extends "layouts/main.tpl"}}
+//	{{define "title"}}Home{{end}}
+//	{{define "content"}}<h1>{{.Title}}</h1>{{end}}
+//
+// the base is compiled with the page into a single template tree,
+// a base may extend another one. Content of page outside blocks is ignored.
+
+var (
+	tplExtendsRegex = regexp.MustCompile(`^\s*{{-?[ ]*extends[ ]+"([^"]+)"[ ]*-?}}`)
+
+	// gonLayoutTemplates caches Layout compiled with page and sections per view path
+	// a nil entry marks layouts which can't be compiled into single tree
+	gonLayoutTemplates = make(map[string]map[string]*template.Template)
+	layoutLock         sync.RWMutex
+
+	errLayoutNotComposable = errors.New("layout uses content fields apart from printing them")
+)
+
+// getTplExtends parses file extending template named at loc of data into t
+// the parent chain is parsed first and a copy of its tree is body of file,
+// then blocks defined in file override the ones of parent
+func getTplExtends(root string, fs http.FileSystem, file, rParent, data string, loc []int, t *template.Template) (*template.Template, [][]string, error) {
+	parent := data[loc[2]:loc[3]]
+	if parent == file {
+		return nil, nil, fmt.Errorf("template: %s extends itself", file)
+	}
+	t, parentSubs, err := getTplDeep(root, fs, parent, rParent, t)
+	if err != nil {
+		return nil, nil, err
+	}
+	pt := t.Lookup(parent)
+	if pt == nil || pt.Tree == nil {
+		return nil, nil, fmt.Errorf("template: %s extends undefined template %s", file, parent)
+	}
+	if _, err = t.AddParseTree(file, pt.Tree.Copy()); err != nil {
+		return nil, nil, err
+	}
+	// blocks are parsed under another name so text outside them
+	// doesn't replace body taken from parent
+	if _, err = t.New(file + "#blocks").Parse(data[loc[1]:]); err != nil {
+		return nil, nil, err
+	}
+	return t.Lookup(file), parentSubs, nil
+}
+
+// layoutTemplate returns Layout of c compiled with its page and sections
+// {{.LayoutContent}} and {{.SectionName}} of layout become calls of their templates.
+// nil is returned when layout can't be compiled so, then
+// page and sections are rendered into Data before layout
+func (c *Controller) layoutTemplate() *template.Template {
+	dir := c.viewPath()
+	targets := map[string]string{"LayoutContent": c.TplName}
+	for name, tpl := range c.LayoutSections {
+		if tpl != "" {
+			targets[name] = tpl
+		}
+	}
+	names := make([]string, 0, len(targets))
+	for name := range targets {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	key := c.Layout
+	for _, name := range names {
+		key += "\x00" + name + "=" + targets[name]
+	}
+
+	layoutLock.RLock()
+	t, ok := gonLayoutTemplates[dir][key]
+	layoutLock.RUnlock()
+	if ok {
+		return t
+	}
+	t, err := composeLayout(dir, c.Layout, targets)
+	if err != nil && err != errLayoutNotComposable {
+		log.Println("layout compose err:", c.Layout, err)
+	}
+	layoutLock.Lock()
+	if gonLayoutTemplates[dir] == nil {
+		gonLayoutTemplates[dir] = make(map[string]*template.Template)
+	}
+	gonLayoutTemplates[dir][key] = t
+	layoutLock.Unlock()
+	return t
+}
+
+// clearLayouts drops compiled layouts of view path dir
+func clearLayouts(dir string) {
+	layoutLock.Lock()
+	delete(gonLayoutTemplates, dir)
+	layoutLock.Unlock()
+}
+
+// composeLayout parses layout and templates of targets of view path dir into one tree
+// targets maps data field name printed by layout to template replacing it
+func composeLayout(dir, layout string, targets map[string]string) (*template.Template, error) {
+	fs := gonTemplateFS()
+	t := template.New(layout).Delims("{{", "}}").Funcs(gonTplFuncMap)
+	t, subMods, err := getTplDeep(dir, fs, layout, "", t)
+	if err != nil {
+		return nil, err
+	}
+	for _, tpl := range targets {
+		if t.Lookup(tpl) != nil {
+			continue
+		}
+		var subs [][]string
+		if _, subs, err = getTplDeep(dir, fs, tpl, "", t); err != nil {
+			return nil, err
+		}
+		subMods = append(subMods, subs...)
+	}
+	if t, err = _getTemplate(t, dir, fs, subMods, viewDirFiles(dir, layout)...); err != nil {
+		return nil, err
+	}
+	if lt := t.Lookup(layout); lt == nil || lt.Tree == nil {
+		return nil, fmt.Errorf("template: undefined layout %s", layout)
+	}
+	// blocks of layout may print content too
+	for _, tpl := range t.Templates() {
+		if tpl.Tree != nil && !rewriteLayoutFields(tpl.Tree.Root, targets) {
+			return nil, errLayoutNotComposable
+		}
+	}
+	return t, nil
+}
+
+// viewDirFiles returns template files of view path dir in same directory as file
+func viewDirFiles(dir, file string) []string {
+	tf := &templateFile{root: dir, files: make(map[string][]string)}
+	Walk(gonTemplateFS(), dir, func(path string, f os.FileInfo, err error) error {
+		return tf.visit(path, f, err)
+	})
+	return tf.files[filepath.Dir(file)]
+}
+
+// rewriteLayoutFields replaces actions printing a field of targets in list
+// with call of its template. false is returned if fields are used otherwise
+func rewriteLayoutFields(list *parse.ListNode, targets map[string]string) bool {
+	if list == nil {
+		return true
+	}
+	for i, node := range list.Nodes {
+		switch n := node.(type) {
+		case *parse.ActionNode:
+			if name, ok := printedField(n.Pipe); ok {
+				if tpl, ok := targets[name]; ok {
+					list.Nodes[i] = templateCall(tpl)
+					continue
+				}
+			}
+			if usesFields(n.Pipe, targets) {
+				return false
+			}
+		case *parse.IfNode:
+			if !rewriteBranch(&n.BranchNode, targets) {
+				return false
+			}
+		case *parse.RangeNode:
+			if !rewriteBranch(&n.BranchNode, targets) {
+				return false
+			}
+		case *parse.WithNode:
+			if !rewriteBranch(&n.BranchNode, targets) {
+				return false
+			}
+		case *parse.TemplateNode:
+			if usesFields(n.Pipe, targets) {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+func rewriteBranch(b *parse.BranchNode, targets map[string]string) bool {
+	return !usesFields(b.Pipe, targets) &&
+		rewriteLayoutFields(b.List, targets) &&
+		rewriteLayoutFields(b.ElseList, targets)
+}
+
+// printedField returns name of field pipe only prints e.g. {{.LayoutContent}}
+func printedField(pipe *parse.PipeNode) (string, bool) {
+	if pipe == nil || len(pipe.Decl) > 0 || len(pipe.Cmds) != 1 || len(pipe.Cmds[0].Args) != 1 {
+		return "", false
+	}
+	field, ok := pipe.Cmds[0].Args[0].(*parse.FieldNode)
+	if !ok || len(field.Ident) != 1 {
+		return "", false
+	}
+	return field.Ident[0], true
+}
+
+// usesFields reports whether pipe refers to a field of targets
+func usesFields(pipe *parse.PipeNode, targets map[string]string) bool {
+	if pipe == nil {
+		return false
+	}
+	for _, cmd := range pipe.Cmds {
+		for _, arg := range cmd.Args {
+			var idents []string
+			switch a := arg.(type) {
+			case *parse.FieldNode:
+				idents = a.Ident
+			case *parse.VariableNode:
+				idents = a.Ident
+			case *parse.ChainNode:
+				idents = a.Field
+			case *parse.PipeNode:
+				if usesFields(a, targets) {
+					return true
+				}
+			}
+			for _, ident := range idents {
+				if _, ok := targets[ident]; ok {
+					return true
+				}
+			}
+		}
+	}
+	return false
+}
+
+// templateCall returns node of {{template "name" .}}
+func templateCall(name string) parse.Node {
+	tree := template.Must(template.New("").Parse(`{{template ` + strconv.Quote(name) + ` .}}`)).Tree
+	return tree.Root.Nodes[0]
+}
@@ -0,0 +1,217 @@
+package ctrl
+
+import (
+	"os"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/mellowarex/gon"
+	"github.com/mellowarex/gon/logs"
+)
+
+var (
+	tplDefineRegex = regexp.MustCompile(`{{[ ]*define[ ]+"([^"]+)"`)
+	// templateWatch guards single reload loop
+	templateWatch sync.Once
+)
+
+func init() {
+	gon.RegisterStartHook(startTemplates)
+}
+
+// startTemplates compiles templates once in production
+// and starts reloading changed templates in development
+func startTemplates() error {
+	if gon.GConfig.EnvMode == gon.DEV {
+		if err := CompileTemplates(); err != nil {
+			logs.Error("template: %v", err)
+		}
+		WatchTemplates()
+		return nil
+	}
+	return CompileTemplates()
+}
+
+// CompileTemplates builds all templates of all view paths
+// the first parse error is returned.
+// gon.Run calls it in production so a broken template stops app from starting
+func CompileTemplates() error {
+	for _, dir := range viewPaths() {
+		if err := buildTemplate(dir); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// viewPaths returns registered view paths
+func viewPaths() []string {
+	templatesLock.RLock()
+	defer templatesLock.RUnlock()
+	dirs := make([]string, 0, len(gonViewPathTemplates))
+	for dir := range gonViewPathTemplates {
+		dirs = append(dirs, dir)
+	}
+	return dirs
+}
+
+// templateBuilt reports whether files are built in view path dir
+func templateBuilt(dir string, files ...string) bool {
+	templatesLock.RLock()
+	defer templatesLock.RUnlock()
+	gonTemplates, ok := gonViewPathTemplates[dir]
+	if !ok {
+		return false
+	}
+	for _, file := range files {
+		if _, ok := gonTemplates[file]; !ok {
+			return false
+		}
+	}
+	return true
+}
+
+// WatchTemplates rebuilds templates of view paths changed on template file system
+// every GConfig.TemplateReloadInterval milliseconds. Only changed files and
+// templates including them are rebuilt. gon.Run calls it in development
+func WatchTemplates() {
+	interval := gon.GConfig.TemplateReloadInterval
+	if interval < 0 {
+		return
+	}
+	if interval == 0 {
+		interval = 1000
+	}
+	templateWatch.Do(func() {
+		w := &templateWatcher{stamps: make(map[string]map[string]fileStamp)}
+		for _, dir := range viewPaths() {
+			w.stamps[dir] = scanTemplates(dir)
+		}
+		go func() {
+			for range time.Tick(time.Duration(interval) * time.Millisecond) {
+				w.poll()
+			}
+		}()
+	})
+}
+
+// fileStamp modification stamp of template file
+type fileStamp struct {
+	modTime time.Time
+	size    int64
+}
+
+// templateWatcher tracks template files of view paths
+type templateWatcher struct {
+	stamps map[string]map[string]fileStamp
+}
+
+// poll rebuilds templates changed since last poll
+func (w *templateWatcher) poll() {
+	for _, dir := range viewPaths() {
+		stamps := scanTemplates(dir)
+		old := w.stamps[dir]
+		w.stamps[dir] = stamps
+
+		var changed, removed []string
+		for file, st := range stamps {
+			if o, ok := old[file]; !ok || o != st {
+				changed = append(changed, file)
+			}
+		}
+		for file := range old {
+			if _, ok := stamps[file]; !ok {
+				removed = append(removed, file)
+			}
+		}
+		if len(changed) == 0 && len(removed) == 0 {
+			continue
+		}
+
+		if len(removed) > 0 {
+			templatesLock.Lock()
+			for _, file := range removed {
+				delete(gonViewPathTemplates[dir], file)
+			}
+			templatesLock.Unlock()
+		}
+		// layouts are compiled with pages and sections from source, see layoutTemplate
+		clearLayouts(dir)
+		files := templateDependents(dir, stamps, append(changed, removed...))
+		if len(files) == 0 {
+			continue
+		}
+		logs.Info("template: rebuilding %s", strings.Join(files, ", "))
+		if err := buildTemplate(dir, files...); err != nil {
+			logs.Error("template: %v", err)
+		}
+	}
+}
+
+// scanTemplates returns stamps of template files in view path dir
+// names are relative to dir like in gonViewPathTemplates
+func scanTemplates(dir string) map[string]fileStamp {
+	stamps := make(map[string]fileStamp)
+	Walk(gonTemplateFS(), dir, func(path string, f os.FileInfo, err error) error {
+		if f == nil || f.IsDir() || !HasTemplateExt(path) {
+			return nil
+		}
+		file := strings.TrimLeft(strings.Replace(path[len(dir):], "\\", "/", -1), "/")
+		stamps[file] = fileStamp{modTime: f.ModTime(), size: f.Size()}
+		return nil
+	})
+	return stamps
+}
+
+// templateDependents returns files of dir to rebuild when changed files change
+// a file depends on files it includes with {{template}} by name,
+// on files defining blocks it includes and on the file it extends
+func templateDependents(dir string, stamps map[string]fileStamp, changed []string) []string {
+	includes := make(map[string][]string) // file -> included names
+	defines := make(map[string][]string)  // name -> files defining it
+	for file := range stamps {
+		src, err := TemplateSource(dir, file)
+		if err != nil {
+			continue
+		}
+		// parent of extends is named by its file
+		if m := tplExtendsRegex.FindSubmatch(src); m != nil {
+			includes[file] = append(includes[file], string(m[1]))
+		}
+		for _, m := range tplIncludeRegex.FindAllStringSubmatch(string(src), -1) {
+			includes[file] = append(includes[file], m[1])
+		}
+		for _, m := range tplDefineRegex.FindAllStringSubmatch(string(src), -1) {
+			defines[m[1]] = append(defines[m[1]], file)
+		}
+	}
+	// dependents[x] files which include x directly
+	dependents := make(map[string][]string)
+	for file, names := range includes {
+		for _, name := range names {
+			dependents[name] = append(dependents[name], file)
+			for _, def := range defines[name] {
+				dependents[def] = append(dependents[def], file)
+			}
+		}
+	}
+
+	seen := make(map[string]bool)
+	queue := append([]string{}, changed...)
+	var files []string
+	for len(queue) > 0 {
+		file := queue[0]
+		queue = queue[1:]
+		if seen[file] {
+			continue
+		}
+		seen[file] = true
+		if _, ok := stamps[file]; ok {
+			files = append(files, file)
+		}
+		queue = append(queue, dependents[file]...)
+	}
+	return files
+}
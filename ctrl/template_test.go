@@ -0,0 +1,97 @@
+package ctrl
+
+import (
+	"html/template"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/mellowarex/gon"
+)
+
+// newBenchViews writes templates to temp view path and builds them
+func newBenchViews(b *testing.B) string {
+	b.Helper()
+	dir, err := ioutil.TempDir("", "gonviews")
+	if err != nil {
+		b.Fatal(err)
+	}
+	b.Cleanup(func() {
+		templatesLock.Lock()
+		delete(gonViewPathTemplates, dir)
+		templatesLock.Unlock()
+		clearLayouts(dir)
+		os.RemoveAll(dir)
+	})
+	files := map[string]string{
+		"layout.tpl": `<html><body>{{.LayoutContent}}</body></html>`,
+		"page.tpl":   `<h1>{{.Title}}</h1><ul>{{range .Items}}<li>{{.}}</li>{{end}}</ul>`,
+	}
+	for name, src := range files {
+		if err := ioutil.WriteFile(filepath.Join(dir, name), []byte(src), 0600); err != nil {
+			b.Fatal(err)
+		}
+	}
+	templatesLock.Lock()
+	gonViewPathTemplates[dir] = make(map[string]*template.Template)
+	templatesLock.Unlock()
+	if err := buildTemplate(dir); err != nil {
+		b.Fatal(err)
+	}
+	return dir
+}
+
+// BenchmarkRender renders page alone and in layout, precompiled as in production
+// where templates are read without lock, and in development where reads lock.
+// rebuild is the baseline which builds the templates on every render
+// as RenderTemplate did before they were compiled on start
+func BenchmarkRender(b *testing.B) {
+	mode := gon.GConfig.EnvMode
+	defer func() { gon.GConfig.EnvMode = mode }()
+	dir := newBenchViews(b)
+	data := map[interface{}]interface{}{
+		"Title": "Items",
+		"Items": []string{"one", "two", "three", "four", "five"},
+	}
+	cases := []struct {
+		name    string
+		env     string
+		rebuild bool
+	}{
+		{"prod", gon.PROD, false},
+		{"dev", gon.DEV, false},
+		{"rebuild", gon.DEV, true},
+	}
+	for _, tc := range cases {
+		for _, layout := range []string{"", "layout.tpl"} {
+			name := tc.name + "/page"
+			files := []string{"page.tpl"}
+			if layout != "" {
+				name += "+layout"
+				files = append(files, layout)
+			}
+			b.Run(name, func(b *testing.B) {
+				gon.GConfig.EnvMode = tc.env
+				b.ReportAllocs()
+				b.RunParallel(func(pb *testing.PB) {
+					c := &Controller{ViewPath: dir, TplName: "page.tpl", Layout: layout}
+					for pb.Next() {
+						if tc.rebuild {
+							if err := buildTemplate(dir, files...); err != nil {
+								b.Fatal(err)
+							}
+						}
+						c.Data = make(map[interface{}]interface{}, len(data)+1)
+						for k, v := range data {
+							c.Data[k] = v
+						}
+						if _, err := c.RenderBytes(); err != nil {
+							b.Fatal(err)
+						}
+					}
+				})
+			})
+		}
+	}
+}
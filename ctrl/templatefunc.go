@@ -2,6 +2,8 @@ package ctrl
 
 import (
 	"github.com/mellowarex/gon"
+	"github.com/mellowarex/gon/i18n"
+	"github.com/mellowarex/gon/logs"
 	"errors"
 	"fmt"
 	"html"
@@ -63,7 +65,11 @@ func HTML2str(html string) string {
 }
 
 // DateFormat takes a time and a layout string and returns a string with the formatted date. Used by the template parser as "dateformat"
-func DateFormat(t time.Time, layout string) (datestring string) {
+// month and day names are translated when locale is given: {{dateformat .Created "2 January 2006" .Lang}}
+func DateFormat(t time.Time, layout string, lang ...string) (datestring string) {
+	if len(lang) > 0 && lang[0] != "" {
+		return i18n.Default.FormatDate(lang[0], t, layout)
+	}
 	datestring = t.Format(layout)
 	return
 }
@@ -143,43 +149,88 @@ func NotNil(a interface{}) (isNil bool) {
 	return CompareNot(a, nil)
 }
 
-// GetConfig get the Appconfig
-// func GetConfig(returnType, key string, defaultVal interface{}) (value interface{}, err error) {
-// 	switch returnType {
-// 	case "String":
-// 		value = GonConfig.String(key)
-// 	case "Bool":
-// 		value, err = GonConfig.Bool(key)
-// 	case "Int":
-// 		value, err = GonConfig.Int(key)
-// 	case "Int64":
-// 		value, err = GonConfig.Int64(key)
-// 	case "Float":
-// 		value, err = GonConfig.Float(key)
-// 	case "DIY":
-// 		value, err = GonConfig.DIY(key)
-// 	default:
-// 		err = errors.New("config keys must be of type String, Bool, Int, Int64, Float, or DIY")
-// 	}
-
-// 	if err != nil {
-// 		if reflect.TypeOf(returnType) != reflect.TypeOf(defaultVal) {
-// 			err = errors.New("defaultVal type does not match returnType")
-// 		} else {
-// 			value, err = defaultVal, nil
-// 		}
-// 	} else if reflect.TypeOf(value).Kind() == reflect.String {
-// 		if value == "" {
-// 			if reflect.TypeOf(defaultVal).Kind() != reflect.String {
-// 				err = errors.New("defaultVal type must be a String if the returnType is a String")
-// 			} else {
-// 				value = defaultVal.(string)
-// 			}
-// 		}
-// 	}
-
-// 	return
-// }
+// GetConfig returns value of GConfig field at dotted key
+// e.g. "AppName", "WebConfig.FlashName" or "StaticDir./static",
+// field names match case insensitively. defaultVal is returned for unknown keys
+// if given. Fields tagged secret:"true" like XSRFKey and values holding them
+// are refused. Used by the template parser as "config": {{config "AppName"}}
+func GetConfig(key string, defaultVal ...interface{}) (interface{}, error) {
+	v := reflect.ValueOf(gon.GConfig)
+	for _, name := range strings.Split(key, ".") {
+		for v.Kind() == reflect.Ptr || v.Kind() == reflect.Interface {
+			if v.IsNil() {
+				break
+			}
+			v = v.Elem()
+		}
+		switch v.Kind() {
+		case reflect.Struct:
+			field, ok := v.Type().FieldByNameFunc(func(field string) bool {
+				return strings.EqualFold(field, name)
+			})
+			if !ok {
+				v = reflect.Value{}
+				break
+			}
+			if field.Tag.Get("secret") == "true" {
+				return nil, errors.New("config key is secret: " + key)
+			}
+			v = v.FieldByIndex(field.Index)
+		case reflect.Map:
+			if v.Type().Key().Kind() != reflect.String {
+				v = reflect.Value{}
+				break
+			}
+			v = v.MapIndex(reflect.ValueOf(name).Convert(v.Type().Key()))
+		default:
+			v = reflect.Value{}
+		}
+		if !v.IsValid() {
+			if len(defaultVal) > 0 {
+				return defaultVal[0], nil
+			}
+			return nil, errors.New("config key not found: " + key)
+		}
+	}
+	if !v.CanInterface() {
+		return nil, errors.New("config key not exported: " + key)
+	}
+	if hasSecret(v.Type(), make(map[reflect.Type]bool)) {
+		return nil, errors.New("config key holds secrets: " + key)
+	}
+	return v.Interface(), nil
+}
+
+// hasSecret reports whether values of t hold fields tagged secret:"true"
+func hasSecret(t reflect.Type, seen map[reflect.Type]bool) bool {
+	if seen[t] {
+		return false
+	}
+	seen[t] = true
+	switch t.Kind() {
+	case reflect.Ptr, reflect.Slice, reflect.Array, reflect.Map:
+		return hasSecret(t.Elem(), seen)
+	case reflect.Struct:
+		for i := 0; i < t.NumField(); i++ {
+			f := t.Field(i)
+			if f.Tag.Get("secret") == "true" || hasSecret(f.Type, seen) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// Asset returns url of static file with content hash for cache busting
+// e.g. {{asset "/static/css/app.css"}} gives /static/css/app.css?v=9f86d081884c7d65.
+// url is returned as is for files not found in StaticDir or StaticFS
+func Asset(urlPath string) string {
+	u, err := gon.AssetURL(urlPath)
+	if err != nil && gon.GConfig.EnvMode == gon.DEV {
+		logs.Warn("asset %s: %v", urlPath, err)
+	}
+	return u
+}
 
 // build URL given uri
 func URLFor(uri, subdomain string) string {
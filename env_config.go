@@ -35,26 +35,66 @@ type Listen struct {
 // WebConfig holds web related config
 type WebConfig struct {
 	FlashName              string
-	FlashSeparator         string
+	FlashSeparator         string // deprecated: flash messages are no longer packed in a string
+	FlashKey               string `secret:"true"` // secret of flash cookie encryption, used when sessions are off
 
 	EnableXSRF             bool
-	XSRFKey                string
+	XSRFKey                string `secret:"true"`
 	XSRFExpire             int
+	XSRFDisableOriginCheck bool     // skip Origin/Referer check of xsrf protected requests
+	XSRFTrustedOrigins     []string // other origins allowed to post e.g. https://app.example.com or *.example.com
 	Session                SessionConfig
+	I18n                   I18nConfig
+	Auth                   AuthConfig
+	JWT                    JWTConfig
+}
+
+// AuthConfig holds authentication config
+type AuthConfig struct {
+	RememberName   string // remember-me cookie name
+	RememberKey    string `secret:"true"` // secret of remember-me cookies, remember-me is off if empty
+	RememberMaxAge int64  // remember-me cookie lifetime in seconds
+}
+
+// I18nConfig holds internationalization config
+type I18nConfig struct {
+	I18nOn      bool
+	Dir         string // directory of catalogs named by locale e.g. en.json, fr.yaml, de.ini
+	DefaultLang string
+	URLPrefix   bool   // read locale from first url path segment e.g. /fr/posts
+	CookieName  string // cookie holding locale chosen by user
+}
+
+// JWTConfig holds token config of jwt.Default
+type JWTConfig struct {
+	Secret   string `secret:"true"` // HS256 secret
+	KeyFile  string // PEM file of RSA or EC private key, used instead of Secret
+	KeyID    string // kid of key
+	Issuer   string
+	Audience string
+	TTL      int64 // lifetime of issued tokens in seconds
+	Leeway   int64 // clock skew in seconds allowed checking exp and nbf
 }
 
 // SessionConfig holds session related config
 type SessionConfig struct {
 	SessionOn                    bool
+	SessionProvider              string // registered session provider name: cookie by default
 	SessionName                  string
 	SessionGCMaxLifetime         int64
-	SessionProviderConfig        string
+	SessionProviderConfig        string `secret:"true"` // provider config: file dir, memory max sessions, sql driver:dsn, redis addr,poolsize,password,dbnum,retries
 	SessionCookieLifeTime        int
 	SessionAutoSetCookie         bool
 	SessionDisableHTTPOnly       bool // used to allow for cross domain cookies/javascript cookies.
 	SessionEnableSidInHTTPHeader bool // enable store/get the sessionId into/from http headers
 	SessionNameInHTTPHeader      string
 	SessionEnableSidInURLQuery   bool // enable get the sessionId from Url Query params
+	SessionDomain                string
+	SessionPath                  string // session cookie path, "/" by default
+	SessionSameSite              string // session cookie SameSite: lax, strict, none or empty to omit
+	SessionLock                  bool   // serialize requests of one session when provider supports it
+	SessionLockTimeout           int64  // lock wait in milliseconds
+	SessionReadOnlyGet           bool   // GET and HEAD requests do not lock session
 }
 
 // LogConfig holds Log related config
@@ -70,7 +110,7 @@ type LogMail struct {
 	SendMail   		bool
 	Env  					string
 	Username			string
-	Password  		string
+	Password  		string `secret:"true"`
 	Host  				string
 	Subject  			string
 	FromAddress   string
@@ -101,11 +141,13 @@ func initEnvConfig() *EnvConfig {
 		WebConfig: WebConfig{
 			FlashName:              "GON_FLASH",
 			FlashSeparator:         "GONFLASH",
+			FlashKey:               "",
 			EnableXSRF:             false,
 			XSRFKey:                "gonxsrf",
 			XSRFExpire:             0,
 			Session: SessionConfig{
 				SessionOn:                    false,
+				SessionProvider:              "cookie",
 				SessionName:                  "gonsession",
 				SessionGCMaxLifetime:         3600,
 				SessionProviderConfig:        "",
@@ -115,6 +157,28 @@ func initEnvConfig() *EnvConfig {
 				SessionEnableSidInHTTPHeader: false, // enable store/get the sessionId into/from http headers
 				SessionNameInHTTPHeader:      "Gonsession",
 				SessionEnableSidInURLQuery:   false, // enable get the sessionId from Url Query params
+				SessionDomain:                "",
+				SessionPath:                  "/",
+				SessionSameSite:              "lax",
+				SessionLock:                  false,
+				SessionLockTimeout:           10000,
+				SessionReadOnlyGet:           false,
+			},
+			I18n: I18nConfig{
+				I18nOn:      false,
+				Dir:         "i18n",
+				DefaultLang: "en",
+				URLPrefix:   false,
+				CookieName:  "lang",
+			},
+			Auth: AuthConfig{
+				RememberName:   "gon_remember",
+				RememberKey:    "",
+				RememberMaxAge: 30 * 24 * 3600,
+			},
+			JWT: JWTConfig{
+				KeyID: "default",
+				TTL:   3600,
 			},
 		},
 		Log: Log{
@@ -212,6 +212,18 @@ func invalidxsrf(rw http.ResponseWriter, r *http.Request) {
 	)
 }
 
+// show 406 Not Acceptable
+func notAcceptable(rw http.ResponseWriter, r *http.Request) {
+	responseError(rw, r,
+		406,
+		"<br>The page you have requested is not available in an acceptable format."+
+			"<br>Perhaps you are here because:"+
+			"<br><br><ul>"+
+			"<br>The Accept header of your request matches no representation of the resource"+
+			"</ul>",
+	)
+}
+
 // show 404 not found error.
 func notFound(rw http.ResponseWriter, r *http.Request) {
 	responseError(rw, r,
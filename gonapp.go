@@ -15,6 +15,8 @@ type HServer struct {
 	Handler *Multiplexer
 	Server 	*http.Server
 	Config  *Config
+
+	middleware []func(http.Handler) http.Handler
 }
 
 var (
@@ -34,9 +36,20 @@ func NewHServer() *HServer {
 	return gon
 }
 
+// Use wraps Handler with middleware when server runs, first one is outermost
+// usage: gon.GonApp.Use(jwt.Default.Middleware)
+func (this *HServer) Use(middleware ...func(http.Handler) http.Handler) {
+	this.middleware = append(this.middleware, middleware...)
+}
+
 // Run start gon web app
 func (this *HServer) Run() {
-	this.Server.Handler = this.Handler
+	runStartHooks()
+	var handler http.Handler = this.Handler
+	for i := len(this.middleware) - 1; i >= 0; i-- {
+		handler = this.middleware[i](handler)
+	}
+	this.Server.Handler = handler
 	this.Server.ReadTimeout = time.Duration(this.Config.Listen.ServerTimeOut) * time.Second
 	this.Server.WriteTimeout = time.Duration(this.Config.Listen.ServerTimeOut) * time.Second
 
@@ -1,17 +1,27 @@
 package gon
 
 import (
+	"fmt"
+	"github.com/mellowarex/gon/auth"
+	"github.com/mellowarex/gon/context"
+	"github.com/mellowarex/gon/i18n"
+	"github.com/mellowarex/gon/jwt"
 	"github.com/mellowarex/gon/session"
+	"github.com/mellowarex/gon/utils"
+	"io/ioutil"
 	"net/http"
 	"path/filepath"
 	"sync"
 	"os"
+	"strings"
+	"time"
 )
 
 type hookfunc func() error
 
 var (
 	hooks = make([]hookfunc, 0) // store slice of hook func
+	startHooks = make([]hookfunc, 0) // hook funcs run by Run
 	initHttp sync.Once
 )
 
@@ -24,11 +34,33 @@ func RegisterHook(hf ...hookfunc) {
 	hooks = append(hooks, hf...)
 }
 
+// RegisterStartHook registers hf to run when app starts serving
+// e.g. packages compile templates after main configured them.
+// An error of hook stops app from starting
+func RegisterStartHook(hf ...func() error) {
+	for _, h := range hf {
+		startHooks = append(startHooks, h)
+	}
+}
+
+// runStartHooks runs hooks registered with RegisterStartHook
+func runStartHooks() {
+	for _, hk := range startHooks {
+		if err := hk(); err != nil {
+			panic(err)
+		}
+	}
+}
+
 func initializeServer() {
 	initHttp.Do(func() {
 		RegisterHook(
 			registerDefaultErrorHandler,
 			registerSession,
+			registerResponseCache,
+			registerI18n,
+			registerAuth,
+			registerJWT,
 			)
 
 		for _, hk := range hooks {
@@ -44,17 +76,28 @@ func registerSession() error {
 		var err error
 		appPath, err := os.Getwd()
 		conf := new(session.CookieConfig)
+		conf.ProviderName = GConfig.WebConfig.Session.SessionProvider
 		conf.CookieName = GConfig.WebConfig.Session.SessionName
 		conf.EnableSetCookie = GConfig.WebConfig.Session.SessionAutoSetCookie
 		conf.Gclifetime = GConfig.WebConfig.Session.SessionGCMaxLifetime
 		conf.Secure = GConfig.Listen.EnableHTTPS
 		conf.CookieLifeTime = GConfig.WebConfig.Session.SessionCookieLifeTime
-		conf.ProviderConfig = filepath.Join(appPath,"config", "cookie" + ".json")
+		conf.ProviderConfig = GConfig.WebConfig.Session.SessionProviderConfig
+		if conf.ProviderConfig == "" && (conf.ProviderName == "" || conf.ProviderName == "cookie") {
+			conf.ProviderConfig = filepath.Join(appPath,"config", "cookie" + ".json")
+		}
 		conf.DisableHTTPOnly = GConfig.WebConfig.Session.SessionDisableHTTPOnly
-		// conf.Domain = GConfig.WebConfig.Session.SessionDomain
+		conf.Domain = GConfig.WebConfig.Session.SessionDomain
+		conf.Path = GConfig.WebConfig.Session.SessionPath
+		if conf.SameSite, err = parseSameSite(GConfig.WebConfig.Session.SessionSameSite); err != nil {
+			return err
+		}
 		conf.EnableSidInHTTPHeader = GConfig.WebConfig.Session.SessionEnableSidInHTTPHeader
 		conf.SessionNameInHTTPHeader = GConfig.WebConfig.Session.SessionNameInHTTPHeader
 		conf.EnableSidInURLQuery = GConfig.WebConfig.Session.SessionEnableSidInURLQuery
+		conf.EnableLock = GConfig.WebConfig.Session.SessionLock
+		conf.LockTimeout = GConfig.WebConfig.Session.SessionLockTimeout
+		conf.ReadOnlyGet = GConfig.WebConfig.Session.SessionReadOnlyGet
 
 		if GlobalSessions, err = session.NewManager(conf); err != nil {
 			return err
@@ -64,6 +107,86 @@ func registerSession() error {
 	return nil
 }
 
+// parseSameSite converts SameSite config value to http.SameSite
+func parseSameSite(v string) (http.SameSite, error) {
+	switch strings.ToLower(v) {
+	case "":
+		return 0, nil
+	case "lax":
+		return http.SameSiteLaxMode, nil
+	case "strict":
+		return http.SameSiteStrictMode, nil
+	case "none":
+		return http.SameSiteNoneMode, nil
+	}
+	return 0, fmt.Errorf("session: invalid SameSite %q, want lax, strict or none", v)
+}
+
+// registerI18n loads message catalogs of I18n.Dir into i18n.Default
+// catalogs embedded in binary may be loaded with i18n.Default.LoadFS instead
+func registerI18n() error {
+	conf := GConfig.WebConfig.I18n
+	if !conf.I18nOn {
+		return nil
+	}
+	if conf.DefaultLang != "" {
+		i18n.Default.DefaultLang = i18n.Canonical(conf.DefaultLang)
+	}
+	if conf.Dir == "" || !utils.FileExists(conf.Dir) {
+		return nil
+	}
+	return i18n.Default.LoadDir(conf.Dir)
+}
+
+// registerAuth sets remember-me cookie of auth package from config
+// remembered logins get a new session id as Controller.Login does
+func registerAuth() error {
+	conf := GConfig.WebConfig.Auth
+	if conf.RememberName != "" {
+		auth.RememberName = conf.RememberName
+	}
+	auth.RememberKey = conf.RememberKey
+	if GConfig.WebConfig.Session.SessionOn {
+		auth.RegenerateSession = func(ctx *context.Context) error {
+			store, err := GlobalSessions.SessionRegenerateID(ctx.ResponseWriter, ctx.Request, ctx.Input.Cookie)
+			if err != nil {
+				return err
+			}
+			ctx.Input.Cookie = store
+			return nil
+		}
+	}
+	return nil
+}
+
+// registerJWT sets key and claims of jwt.Default from config
+func registerJWT() error {
+	conf := GConfig.WebConfig.JWT
+	var key *jwt.Key
+	switch {
+	case conf.KeyFile != "":
+		data, err := ioutil.ReadFile(conf.KeyFile)
+		if err != nil {
+			return err
+		}
+		if key, err = jwt.ParseKeyPEM(conf.KeyID, data); err != nil {
+			return err
+		}
+	case conf.Secret != "":
+		key = jwt.NewHS256(conf.KeyID, []byte(conf.Secret))
+	}
+	if key != nil {
+		jwt.Default.Keys.Rotate(key)
+	}
+	jwt.Default.Issuer = conf.Issuer
+	jwt.Default.Audience = conf.Audience
+	if conf.TTL > 0 {
+		jwt.Default.TTL = time.Duration(conf.TTL) * time.Second
+	}
+	jwt.Default.Leeway = time.Duration(conf.Leeway) * time.Second
+	return nil
+}
+
 // register default error http handlers, 404,401,403,500 and 503.
 func registerDefaultErrorHandler() error {
 	m := map[string]func(http.ResponseWriter, *http.Request){
@@ -72,6 +195,7 @@ func registerDefaultErrorHandler() error {
 		"403": forbidden,
 		"404": notFound,
 		"405": methodNotAllowed,
+		"406": notAcceptable,
 		"500": internalServerError,
 		"501": notImplemented,
 		"502": badGateway,
@@ -0,0 +1,51 @@
+package i18n
+
+import (
+	"strings"
+	"time"
+)
+
+// names of layout replaced by translations of keys date.<name>
+// e.g. date.January, date.Jan, date.Monday, date.Mon, date.PM
+// longer names first so January isn't read as Jan
+var dateNames = []string{"January", "Monday", "Jan", "Mon", "PM", "pm"}
+
+// FormatDate formats t by Go layout in locale lang
+// month and day names come from catalog keys date.January ... date.December,
+// date.Jan ... date.Dec, date.Monday ... date.Sunday, date.Mon ... date.Sun
+// and date.AM/date.PM, English names are kept for missing keys
+func (b *Bundle) FormatDate(lang string, t time.Time, layout string) string {
+	var out strings.Builder
+	for layout != "" {
+		i, name := nextDateName(layout)
+		if i < 0 {
+			out.WriteString(t.Format(layout))
+			break
+		}
+		if i > 0 {
+			out.WriteString(t.Format(layout[:i]))
+		}
+		english := t.Format(name)
+		key := "date." + english
+		if name == "pm" {
+			key = "date." + strings.ToUpper(english)
+		}
+		if msg, ok := b.Lookup(lang, key); ok {
+			english = msg
+		}
+		out.WriteString(english)
+		layout = layout[i+len(name):]
+	}
+	return out.String()
+}
+
+// nextDateName returns index and name of first date name in layout
+func nextDateName(layout string) (int, string) {
+	first, name := -1, ""
+	for _, n := range dateNames {
+		if i := strings.Index(layout, n); i >= 0 && (first < 0 || i < first) {
+			first, name = i, n
+		}
+	}
+	return first, name
+}
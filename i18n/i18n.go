@@ -0,0 +1,387 @@
+// Package i18n provides message catalogs per locale
+// with plural forms, fallbacks and locale negotiation
+// Usage:
+//
+//	i18n.Default.LoadDir("i18n") // en.json, fr.yaml, de.ini ...
+//	i18n.Tr("fr", "home.title")
+//	i18n.Tr("fr", "inbox.messages", 3)
+//
+// catalogs nest keys which are joined with dots:
+//
+//	{
+//	  "home": {"title": "Accueil"},
+//	  "inbox": {"messages": {"one": "%d message", "other": "%d messages"}}
+//	}
+//
+// a key with plural forms holds them as sub keys named by plural category:
+// zero, one, two, few, many and other. Translations are chosen by the first
+// numeric argument and formatted with fmt.Sprintf when given arguments.
+// In INI files sections prefix keys:
+//
+//	[inbox]
+//	messages.one   = %d message
+//	messages.other = %d messages
+package i18n
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"gopkg.in/yaml.v2"
+)
+
+// DataKey key of context input data holding locale read from url path
+const DataKey = "i18n.lang"
+
+// SessionKey session key holding locale chosen by user
+const SessionKey = "_lang"
+
+// Default bundle used by package funcs, controllers and templates
+var Default = NewBundle("en")
+
+// Bundle message catalogs of locales
+type Bundle struct {
+	// DefaultLang locale used when no other one matches
+	DefaultLang string
+
+	lock      sync.RWMutex
+	catalogs  map[string]map[string]string // lang -> key -> message
+	fallbacks map[string][]string
+}
+
+// NewBundle returns empty bundle with default locale defaultLang
+func NewBundle(defaultLang string) *Bundle {
+	return &Bundle{
+		DefaultLang: Canonical(defaultLang),
+		catalogs:    make(map[string]map[string]string),
+		fallbacks:   make(map[string][]string),
+	}
+}
+
+// Tr translates key to locale lang of Default bundle
+func Tr(lang, key string, args ...interface{}) string {
+	return Default.Tr(lang, key, args...)
+}
+
+// Canonical returns locale tag lang in form ll or ll-RR e.g. pt_br gives pt-BR
+func Canonical(lang string) string {
+	lang = strings.TrimSpace(strings.Replace(lang, "_", "-", -1))
+	parts := strings.Split(lang, "-")
+	parts[0] = strings.ToLower(parts[0])
+	for i := 1; i < len(parts); i++ {
+		if len(parts[i]) == 2 {
+			parts[i] = strings.ToUpper(parts[i])
+		} else if len(parts[i]) == 4 {
+			parts[i] = strings.ToUpper(parts[i][:1]) + strings.ToLower(parts[i][1:])
+		}
+	}
+	return strings.Join(parts, "-")
+}
+
+// base returns language of locale tag e.g. pt of pt-BR
+func base(lang string) string {
+	if i := strings.Index(lang, "-"); i > 0 {
+		return lang[:i]
+	}
+	return lang
+}
+
+// SetFallback sets locales tried in order when lang has not a key
+// before its base language and DefaultLang
+func (b *Bundle) SetFallback(lang string, fallbacks ...string) {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+	list := make([]string, len(fallbacks))
+	for i, f := range fallbacks {
+		list[i] = Canonical(f)
+	}
+	b.fallbacks[Canonical(lang)] = list
+}
+
+// Langs returns sorted loaded locales
+func (b *Bundle) Langs() []string {
+	b.lock.RLock()
+	defer b.lock.RUnlock()
+	langs := make([]string, 0, len(b.catalogs))
+	for lang := range b.catalogs {
+		langs = append(langs, lang)
+	}
+	sort.Strings(langs)
+	return langs
+}
+
+// Has reports whether catalog of lang is loaded
+func (b *Bundle) Has(lang string) bool {
+	b.lock.RLock()
+	defer b.lock.RUnlock()
+	_, ok := b.catalogs[Canonical(lang)]
+	return ok
+}
+
+// Match returns loaded locale best serving lang or empty string
+// lang itself, its base language or a locale of same language
+func (b *Bundle) Match(lang string) string {
+	if lang == "" {
+		return ""
+	}
+	lang = Canonical(lang)
+	b.lock.RLock()
+	defer b.lock.RUnlock()
+	if _, ok := b.catalogs[lang]; ok {
+		return lang
+	}
+	if _, ok := b.catalogs[base(lang)]; ok {
+		return base(lang)
+	}
+	var match string
+	for l := range b.catalogs {
+		if base(l) == base(lang) && (match == "" || l < match) {
+			match = l
+		}
+	}
+	return match
+}
+
+// chain returns locales searched for message of lang in order
+// caller must hold lock
+func (b *Bundle) chain(lang string) []string {
+	lang = Canonical(lang)
+	var chain []string
+	seen := make(map[string]bool)
+	add := func(langs ...string) {
+		for _, l := range langs {
+			if l != "" && !seen[l] {
+				seen[l] = true
+				chain = append(chain, l)
+			}
+		}
+	}
+	add(lang)
+	add(b.fallbacks[lang]...)
+	add(base(lang))
+	add(b.fallbacks[base(lang)]...)
+	add(b.DefaultLang)
+	return chain
+}
+
+// Tr translates key to locale lang
+// the plural form is chosen by the first numeric argument,
+// key is returned when no locale of chain has it
+func (b *Bundle) Tr(lang, key string, args ...interface{}) string {
+	msg, ok := b.Lookup(lang, key, args...)
+	if !ok {
+		return key
+	}
+	if len(args) > 0 && strings.Contains(msg, "%") {
+		return fmt.Sprintf(msg, args...)
+	}
+	return msg
+}
+
+// Lookup returns message of key for locale lang unformatted
+func (b *Bundle) Lookup(lang, key string, args ...interface{}) (string, bool) {
+	n, plural := pluralCount(args)
+	b.lock.RLock()
+	defer b.lock.RUnlock()
+	for _, l := range b.chain(lang) {
+		cat, ok := b.catalogs[l]
+		if !ok {
+			continue
+		}
+		if plural {
+			if n == 0 {
+				if msg, ok := cat[key+".zero"]; ok {
+					return msg, true
+				}
+			}
+			if msg, ok := cat[key+"."+PluralForm(l, n)]; ok {
+				return msg, true
+			}
+			if msg, ok := cat[key+"."+Other]; ok {
+				return msg, true
+			}
+		}
+		if msg, ok := cat[key]; ok {
+			return msg, true
+		}
+	}
+	return "", false
+}
+
+// pluralCount returns first numeric argument
+func pluralCount(args []interface{}) (float64, bool) {
+	if len(args) == 0 {
+		return 0, false
+	}
+	v := reflect.ValueOf(args[0])
+	switch v.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return float64(v.Int()), true
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return float64(v.Uint()), true
+	case reflect.Float32, reflect.Float64:
+		return v.Float(), true
+	}
+	return 0, false
+}
+
+// AddMessages adds messages keyed by dotted keys to catalog of lang
+func (b *Bundle) AddMessages(lang string, msgs map[string]string) {
+	lang = Canonical(lang)
+	b.lock.Lock()
+	defer b.lock.Unlock()
+	cat, ok := b.catalogs[lang]
+	if !ok {
+		cat = make(map[string]string)
+		b.catalogs[lang] = cat
+	}
+	for k, v := range msgs {
+		cat[k] = v
+	}
+}
+
+// Load parses catalog data of format json, yaml or ini into lang
+func (b *Bundle) Load(lang, format string, data []byte) error {
+	msgs := make(map[string]string)
+	switch strings.ToLower(strings.TrimPrefix(format, ".")) {
+	case "json":
+		var v map[string]interface{}
+		if err := json.Unmarshal(data, &v); err != nil {
+			return err
+		}
+		flatten("", v, msgs)
+	case "yaml", "yml":
+		var v map[interface{}]interface{}
+		if err := yaml.Unmarshal(data, &v); err != nil {
+			return err
+		}
+		flatten("", v, msgs)
+	case "ini":
+		if err := parseINI(data, msgs); err != nil {
+			return err
+		}
+	default:
+		return fmt.Errorf("i18n: unknown catalog format %q", format)
+	}
+	b.AddMessages(lang, msgs)
+	return nil
+}
+
+// LoadFile loads catalog file named by its locale e.g. i18n/fr-FR.yaml
+func (b *Bundle) LoadFile(file string) error {
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return err
+	}
+	ext := filepath.Ext(file)
+	if err := b.Load(strings.TrimSuffix(filepath.Base(file), ext), ext, data); err != nil {
+		return fmt.Errorf("i18n: %s: %v", file, err)
+	}
+	return nil
+}
+
+// LoadDir loads catalogs of directory dir, see LoadFS
+func (b *Bundle) LoadDir(dir string) error {
+	return b.LoadFS(os.DirFS(dir), ".")
+}
+
+// LoadFS loads catalogs under dir of fsys e.g. embed.FS
+// files are named by locale like en.json or grouped in directory
+// of locale like fr/forms.ini. Files of other extensions are skipped
+func (b *Bundle) LoadFS(fsys fs.FS, dir string) error {
+	return fs.WalkDir(fsys, dir, func(name string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return err
+		}
+		ext := path.Ext(name)
+		switch strings.ToLower(ext) {
+		case ".json", ".yaml", ".yml", ".ini":
+		default:
+			return nil
+		}
+		rel := strings.TrimPrefix(strings.TrimPrefix(name, dir), "/")
+		lang := strings.TrimSuffix(rel, ext)
+		if i := strings.Index(rel, "/"); i > 0 {
+			lang = rel[:i]
+		}
+		data, err := fs.ReadFile(fsys, name)
+		if err != nil {
+			return err
+		}
+		if err := b.Load(lang, ext, data); err != nil {
+			return fmt.Errorf("i18n: %s: %v", name, err)
+		}
+		return nil
+	})
+}
+
+// flatten adds values of nested map v to msgs with dotted keys
+func flatten(prefix string, v interface{}, msgs map[string]string) {
+	join := func(k string) string {
+		if prefix == "" {
+			return k
+		}
+		return prefix + "." + k
+	}
+	switch m := v.(type) {
+	case map[string]interface{}:
+		for k, val := range m {
+			flatten(join(k), val, msgs)
+		}
+	case map[interface{}]interface{}:
+		for k, val := range m {
+			flatten(join(fmt.Sprint(k)), val, msgs)
+		}
+	case nil:
+	default:
+		msgs[prefix] = fmt.Sprint(m)
+	}
+}
+
+// parseINI parses ini catalog, sections prefix keys
+// values may be double quoted to keep spaces or use escapes
+func parseINI(data []byte, msgs map[string]string) error {
+	section := ""
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for n := 1; scanner.Scan(); n++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || line[0] == ';' || line[0] == '#' {
+			continue
+		}
+		if line[0] == '[' {
+			if line[len(line)-1] != ']' {
+				return fmt.Errorf("line %d: bad section", n)
+			}
+			section = strings.TrimSpace(line[1 : len(line)-1])
+			continue
+		}
+		i := strings.IndexAny(line, "=:")
+		if i <= 0 {
+			return fmt.Errorf("line %d: expected key = value", n)
+		}
+		key, value := strings.TrimSpace(line[:i]), strings.TrimSpace(line[i+1:])
+		if len(value) > 1 && value[0] == '"' {
+			v, err := strconv.Unquote(value)
+			if err != nil {
+				return fmt.Errorf("line %d: %v", n, err)
+			}
+			value = v
+		}
+		if section != "" {
+			key = section + "." + key
+		}
+		msgs[key] = value
+	}
+	return scanner.Err()
+}
@@ -0,0 +1,86 @@
+package i18n
+
+import (
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// ParseAcceptLanguage returns locales of Accept-Language header
+// ordered by quality, locales of quality 0 are dropped
+func ParseAcceptLanguage(header string) []string {
+	type tag struct {
+		lang string
+		q    float64
+	}
+	var tags []tag
+	for _, part := range strings.Split(header, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		lang, q := part, 1.0
+		if i := strings.Index(part, ";"); i >= 0 {
+			lang = strings.TrimSpace(part[:i])
+			if p := strings.TrimSpace(part[i+1:]); strings.HasPrefix(p, "q=") {
+				if v, err := strconv.ParseFloat(p[2:], 64); err == nil {
+					q = v
+				}
+			}
+		}
+		if q <= 0 || lang == "" || lang == "*" {
+			continue
+		}
+		tags = append(tags, tag{Canonical(lang), q})
+	}
+	sort.SliceStable(tags, func(i, j int) bool { return tags[i].q > tags[j].q })
+	langs := make([]string, len(tags))
+	for i, t := range tags {
+		langs[i] = t.lang
+	}
+	return langs
+}
+
+// Negotiate returns loaded locale best matching Accept-Language header
+// or empty string
+func (b *Bundle) Negotiate(header string) string {
+	for _, lang := range ParseAcceptLanguage(header) {
+		if m := b.Match(lang); m != "" {
+			return m
+		}
+	}
+	return ""
+}
+
+// Detect returns locale of request r
+// candidates e.g. read from url, cookie and session are tried in order,
+// then Accept-Language header and DefaultLang
+func (b *Bundle) Detect(r *http.Request, candidates ...string) string {
+	for _, lang := range candidates {
+		if m := b.Match(lang); m != "" {
+			return m
+		}
+	}
+	if m := b.Negotiate(r.Header.Get("Accept-Language")); m != "" {
+		return m
+	}
+	return b.DefaultLang
+}
+
+// SplitPath splits leading locale segment of url path e.g. /fr/posts
+// gives fr and /posts. Only loaded locales are split
+func (b *Bundle) SplitPath(urlPath string) (lang, rest string, ok bool) {
+	if len(urlPath) < 2 || urlPath[0] != '/' {
+		return "", urlPath, false
+	}
+	seg := urlPath[1:]
+	rest = "/"
+	if i := strings.Index(seg, "/"); i >= 0 {
+		seg, rest = seg[:i], seg[i:]
+	}
+	if seg == "" || !b.Has(seg) {
+		return "", urlPath, false
+	}
+	return Canonical(seg), rest, true
+}
@@ -0,0 +1,156 @@
+package i18n
+
+import (
+	"math"
+	"sync"
+)
+
+// plural categories of CLDR
+const (
+	Zero  = "zero"
+	One   = "one"
+	Two   = "two"
+	Few   = "few"
+	Many  = "many"
+	Other = "other"
+)
+
+// PluralRule returns plural category of count n
+type PluralRule func(n float64) string
+
+var (
+	pluralRules = map[string]PluralRule{}
+	pluralLock  sync.RWMutex
+)
+
+func init() {
+	for _, lang := range []string{"fr", "pt", "hi", "bn", "fa", "am", "zu"} {
+		pluralRules[lang] = pluralZeroOne
+	}
+	for _, lang := range []string{"ja", "zh", "ko", "vi", "th", "id", "ms", "lo", "my"} {
+		pluralRules[lang] = pluralNone
+	}
+	for _, lang := range []string{"ru", "uk", "be", "sr", "hr", "bs"} {
+		pluralRules[lang] = pluralSlavic
+	}
+	for _, lang := range []string{"cs", "sk"} {
+		pluralRules[lang] = pluralCzech
+	}
+	pluralRules["pl"] = pluralPolish
+	pluralRules["ar"] = pluralArabic
+	pluralRules["pt-PT"] = pluralOne
+}
+
+// RegisterPluralRule sets plural rule of locale lang or of its language
+func RegisterPluralRule(lang string, rule PluralRule) {
+	pluralLock.Lock()
+	defer pluralLock.Unlock()
+	pluralRules[Canonical(lang)] = rule
+}
+
+// PluralForm returns plural category of n in locale lang
+// languages without registered rule use the English one
+func PluralForm(lang string, n float64) string {
+	lang = Canonical(lang)
+	pluralLock.RLock()
+	rule, ok := pluralRules[lang]
+	if !ok {
+		rule, ok = pluralRules[base(lang)]
+	}
+	pluralLock.RUnlock()
+	if !ok {
+		rule = pluralOne
+	}
+	return rule(n)
+}
+
+// isInt reports whether n is integer
+func isInt(n float64) bool {
+	return n == math.Trunc(n)
+}
+
+// pluralOne English like: one for 1
+func pluralOne(n float64) string {
+	if n == 1 {
+		return One
+	}
+	return Other
+}
+
+// pluralZeroOne French like: one for 0 and 1
+func pluralZeroOne(n float64) string {
+	if n >= 0 && n < 2 {
+		return One
+	}
+	return Other
+}
+
+// pluralNone languages without plural forms
+func pluralNone(n float64) string {
+	return Other
+}
+
+// pluralSlavic Russian like: one 1, 21; few 2-4, 22-24; many 0, 5-20, 25-30
+func pluralSlavic(n float64) string {
+	if !isInt(n) {
+		return Other
+	}
+	i := int64(math.Abs(n))
+	switch {
+	case i%10 == 1 && i%100 != 11:
+		return One
+	case i%10 >= 2 && i%10 <= 4 && (i%100 < 12 || i%100 > 14):
+		return Few
+	}
+	return Many
+}
+
+// pluralPolish one 1; few 2-4, 22-24; many others
+func pluralPolish(n float64) string {
+	if !isInt(n) {
+		return Other
+	}
+	i := int64(math.Abs(n))
+	switch {
+	case i == 1:
+		return One
+	case i%10 >= 2 && i%10 <= 4 && (i%100 < 12 || i%100 > 14):
+		return Few
+	}
+	return Many
+}
+
+// pluralCzech one 1; few 2-4
+func pluralCzech(n float64) string {
+	if !isInt(n) {
+		return Many
+	}
+	switch {
+	case n == 1:
+		return One
+	case n >= 2 && n <= 4:
+		return Few
+	}
+	return Other
+}
+
+// pluralArabic zero 0; one 1; two 2; few 3-10; many 11-99
+func pluralArabic(n float64) string {
+	if !isInt(n) {
+		return Other
+	}
+	i := int64(math.Abs(n))
+	switch {
+	case i == 0:
+		return Zero
+	case i == 1:
+		return One
+	case i == 2:
+		return Two
+	case i%100 >= 3 && i%100 <= 10:
+		return Few
+	case i%100 >= 11:
+		return Many
+	}
+	return Other
+}
@@ -0,0 +1,328 @@
+// Package jwt issues and verifies JSON Web Tokens signed with
+// HS256, RS256 or ES256
+// Usage:
+//
+//	key, _ := jwt.ParseKeyPEM("2024-01", pemData)
+//	jwt.Default = jwt.NewManager(jwt.NewKeySet(key))
+//	jwt.Default.Issuer = "https://api.example.com"
+//
+//	token, err := jwt.Default.Issue(&jwt.Claims{Subject: "42"})
+//	claims, err := jwt.Default.Verify(token)
+package jwt
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"strings"
+	"time"
+)
+
+// verification errors
+var (
+	ErrMalformed   = errors.New("jwt: malformed token")
+	ErrAlgorithm   = errors.New("jwt: unexpected signing algorithm")
+	ErrUnknownKey  = errors.New("jwt: unknown signing key")
+	ErrNoKey       = errors.New("jwt: no signing key")
+	ErrSignature   = errors.New("jwt: invalid signature")
+	ErrExpired     = errors.New("jwt: token is expired")
+	ErrNoExpiry    = errors.New("jwt: token has no expiry")
+	ErrNotValidYet = errors.New("jwt: token is not valid yet")
+	ErrAudience    = errors.New("jwt: invalid audience")
+	ErrIssuer      = errors.New("jwt: invalid issuer")
+)
+
+// Claims of token, registered ones are fields and private ones in Extra
+type Claims struct {
+	Issuer    string   // iss
+	Subject   string   // sub
+	Audience  []string // aud
+	ExpiresAt int64    // exp, unix seconds
+	NotBefore int64    // nbf, unix seconds
+	IssuedAt  int64    // iat, unix seconds
+	ID        string   // jti
+
+	Extra map[string]interface{}
+}
+
+// Get returns private claim of name
+func (c *Claims) Get(name string) interface{} {
+	return c.Extra[name]
+}
+
+// Set sets private claim of name
+func (c *Claims) Set(name string, value interface{}) *Claims {
+	if c.Extra == nil {
+		c.Extra = make(map[string]interface{})
+	}
+	c.Extra[name] = value
+	return c
+}
+
+// Roles returns "roles" claim, so claims of verified tokens
+// are users of auth.RBAC
+func (c *Claims) Roles() []string {
+	switch v := c.Extra["roles"].(type) {
+	case []string:
+		return v
+	case []interface{}:
+		roles := make([]string, 0, len(v))
+		for _, r := range v {
+			if s, ok := r.(string); ok {
+				roles = append(roles, s)
+			}
+		}
+		return roles
+	}
+	return nil
+}
+
+// HasAudience reports whether aud claim contains aud
+func (c *Claims) HasAudience(aud string) bool {
+	for _, a := range c.Audience {
+		if a == aud {
+			return true
+		}
+	}
+	return false
+}
+
+// MarshalJSON implements json.Marshaler
+func (c *Claims) MarshalJSON() ([]byte, error) {
+	m := make(map[string]interface{}, len(c.Extra)+7)
+	for k, v := range c.Extra {
+		m[k] = v
+	}
+	set := func(name string, v interface{}, ok bool) {
+		if ok {
+			m[name] = v
+		}
+	}
+	set("iss", c.Issuer, c.Issuer != "")
+	set("sub", c.Subject, c.Subject != "")
+	if len(c.Audience) == 1 {
+		m["aud"] = c.Audience[0]
+	} else {
+		set("aud", c.Audience, len(c.Audience) > 1)
+	}
+	set("exp", c.ExpiresAt, c.ExpiresAt != 0)
+	set("nbf", c.NotBefore, c.NotBefore != 0)
+	set("iat", c.IssuedAt, c.IssuedAt != 0)
+	set("jti", c.ID, c.ID != "")
+	return json.Marshal(m)
+}
+
+// UnmarshalJSON implements json.Unmarshaler
+func (c *Claims) UnmarshalJSON(data []byte) error {
+	var m map[string]interface{}
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	if err := dec.Decode(&m); err != nil {
+		return err
+	}
+	var err error
+	str := func(name string) string {
+		v, ok := m[name]
+		if !ok {
+			return ""
+		}
+		delete(m, name)
+		s, ok := v.(string)
+		if !ok && err == nil {
+			err = ErrMalformed
+		}
+		return s
+	}
+	num := func(name string) int64 {
+		v, ok := m[name]
+		if !ok {
+			return 0
+		}
+		delete(m, name)
+		n, ok := v.(json.Number)
+		if !ok {
+			if err == nil {
+				err = ErrMalformed
+			}
+			return 0
+		}
+		f, e := n.Float64()
+		if e != nil && err == nil {
+			err = ErrMalformed
+		}
+		return int64(f)
+	}
+	c.Issuer = str("iss")
+	c.Subject = str("sub")
+	c.ID = str("jti")
+	c.ExpiresAt = num("exp")
+	c.NotBefore = num("nbf")
+	c.IssuedAt = num("iat")
+	c.Audience = nil
+	switch aud := m["aud"].(type) {
+	case nil:
+	case string:
+		c.Audience = []string{aud}
+	case []interface{}:
+		for _, a := range aud {
+			s, ok := a.(string)
+			if !ok {
+				return ErrMalformed
+			}
+			c.Audience = append(c.Audience, s)
+		}
+	default:
+		return ErrMalformed
+	}
+	delete(m, "aud")
+	if len(m) > 0 {
+		c.Extra = m
+	}
+	return err
+}
+
+// header of token
+type header struct {
+	Alg string `json:"alg"`
+	Typ string `json:"typ,omitempty"`
+	Kid string `json:"kid,omitempty"`
+}
+
+// Manager issues tokens signed by current key of Keys
+// and verifies tokens signed by any of them
+type Manager struct {
+	Keys     *KeySet
+	Issuer   string        // iss of issued tokens, required of verified ones if set
+	Audience string        // aud of issued tokens, required of verified ones if set
+	TTL      time.Duration // lifetime of issued tokens, one hour if 0
+	Leeway   time.Duration // clock skew allowed checking exp and nbf
+
+	// AllowNoExpiry accepts tokens without exp claim
+	AllowNoExpiry bool
+}
+
+// Default manager used by Controller.IssueToken, set from WebConfig.JWT by gon
+var Default = NewManager(NewKeySet())
+
+// NewManager returns Manager of keys
+func NewManager(keys *KeySet) *Manager {
+	return &Manager{Keys: keys, TTL: time.Hour}
+}
+
+// Issue returns token of claims signed by current key
+// iss, aud, iat and exp are set from manager unless claims have them
+func (m *Manager) Issue(claims *Claims) (string, error) {
+	c := *claims
+	now := time.Now()
+	if c.Issuer == "" {
+		c.Issuer = m.Issuer
+	}
+	if len(c.Audience) == 0 && m.Audience != "" {
+		c.Audience = []string{m.Audience}
+	}
+	if c.IssuedAt == 0 {
+		c.IssuedAt = now.Unix()
+	}
+	if c.ExpiresAt == 0 {
+		ttl := m.TTL
+		if ttl <= 0 {
+			ttl = time.Hour
+		}
+		c.ExpiresAt = now.Add(ttl).Unix()
+	}
+	return m.Sign(&c)
+}
+
+// Sign returns token of claims as given signed by current key
+func (m *Manager) Sign(claims *Claims) (string, error) {
+	key := m.Keys.Current()
+	if key == nil {
+		return "", ErrNoKey
+	}
+	h, err := json.Marshal(header{Alg: key.Alg, Typ: "JWT", Kid: key.ID})
+	if err != nil {
+		return "", err
+	}
+	p, err := json.Marshal(claims)
+	if err != nil {
+		return "", err
+	}
+	signing := b64(h) + "." + b64(p)
+	sig, err := key.sign([]byte(signing))
+	if err != nil {
+		return "", err
+	}
+	return signing + "." + b64(sig), nil
+}
+
+// Verify returns claims of token after checking its signature,
+// exp, nbf and, if set on manager, iss and aud
+func (m *Manager) Verify(token string) (*Claims, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return nil, ErrMalformed
+	}
+	hb, err := unb64(parts[0])
+	if err != nil {
+		return nil, ErrMalformed
+	}
+	var h header
+	if err := json.Unmarshal(hb, &h); err != nil {
+		return nil, ErrMalformed
+	}
+	var key *Key
+	if h.Kid != "" {
+		key = m.Keys.Key(h.Kid)
+	} else {
+		key = m.Keys.Current()
+	}
+	if key == nil {
+		return nil, ErrUnknownKey
+	}
+	// algorithm is the key's, never the token's e.g. "none" or HS256 with RSA public key
+	if h.Alg != key.Alg {
+		return nil, ErrAlgorithm
+	}
+	sig, err := unb64(parts[2])
+	if err != nil {
+		return nil, ErrMalformed
+	}
+	if err := key.verify([]byte(parts[0]+"."+parts[1]), sig); err != nil {
+		return nil, err
+	}
+	pb, err := unb64(parts[1])
+	if err != nil {
+		return nil, ErrMalformed
+	}
+	claims := new(Claims)
+	if err := json.Unmarshal(pb, claims); err != nil {
+		return nil, ErrMalformed
+	}
+	if err := m.Validate(claims); err != nil {
+		return nil, err
+	}
+	return claims, nil
+}
+
+// Validate checks exp, nbf, iss and aud of claims
+func (m *Manager) Validate(c *Claims) error {
+	now := time.Now()
+	leeway := int64(m.Leeway / time.Second)
+	if c.ExpiresAt == 0 {
+		if !m.AllowNoExpiry {
+			return ErrNoExpiry
+		}
+	} else if now.Unix() >= c.ExpiresAt+leeway {
+		return ErrExpired
+	}
+	if c.NotBefore != 0 && now.Unix()+leeway < c.NotBefore {
+		return ErrNotValidYet
+	}
+	if m.Issuer != "" && c.Issuer != m.Issuer {
+		return ErrIssuer
+	}
+	if m.Audience != "" && !c.HasAudience(m.Audience) {
+		return ErrAudience
+	}
+	return nil
+}
@@ -0,0 +1,186 @@
+package jwt
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"math/big"
+)
+
+// supported signing algorithms
+const (
+	HS256 = "HS256"
+	RS256 = "RS256"
+	ES256 = "ES256"
+)
+
+// Key signs and verifies tokens with one algorithm
+// keys having only public part verify tokens only
+type Key struct {
+	ID  string // kid header of tokens signed by key
+	Alg string
+
+	secret  []byte
+	private crypto.Signer
+	public  crypto.PublicKey
+}
+
+// NewHS256 returns HMAC SHA-256 key of secret
+// secret should be at least 32 random bytes
+func NewHS256(kid string, secret []byte) *Key {
+	return &Key{ID: kid, Alg: HS256, secret: secret}
+}
+
+// NewRS256 returns RSA PKCS#1 v1.5 SHA-256 key
+func NewRS256(kid string, key *rsa.PrivateKey) *Key {
+	return &Key{ID: kid, Alg: RS256, private: key, public: &key.PublicKey}
+}
+
+// NewES256 returns ECDSA P-256 SHA-256 key
+func NewES256(kid string, key *ecdsa.PrivateKey) (*Key, error) {
+	if key.Curve != elliptic.P256() {
+		return nil, errors.New("jwt: ES256 key must be on P-256 curve")
+	}
+	return &Key{ID: kid, Alg: ES256, private: key, public: &key.PublicKey}, nil
+}
+
+// NewPublicKey returns key verifying tokens of RSA or P-256 ECDSA public key
+func NewPublicKey(kid string, pub crypto.PublicKey) (*Key, error) {
+	switch p := pub.(type) {
+	case *rsa.PublicKey:
+		return &Key{ID: kid, Alg: RS256, public: p}, nil
+	case *ecdsa.PublicKey:
+		if p.Curve != elliptic.P256() {
+			return nil, errors.New("jwt: ES256 key must be on P-256 curve")
+		}
+		return &Key{ID: kid, Alg: ES256, public: p}, nil
+	}
+	return nil, fmt.Errorf("jwt: unsupported public key %T", pub)
+}
+
+// ParseKeyPEM returns key of PEM encoded RSA or EC private key
+// (PKCS#1, SEC 1 or PKCS#8) or public key (PKIX)
+func ParseKeyPEM(kid string, data []byte) (*Key, error) {
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, errors.New("jwt: no PEM data found")
+	}
+	switch block.Type {
+	case "RSA PRIVATE KEY":
+		key, err := x509.ParsePKCS1PrivateKey(block.Bytes)
+		if err != nil {
+			return nil, err
+		}
+		return NewRS256(kid, key), nil
+	case "EC PRIVATE KEY":
+		key, err := x509.ParseECPrivateKey(block.Bytes)
+		if err != nil {
+			return nil, err
+		}
+		return NewES256(kid, key)
+	case "PRIVATE KEY":
+		key, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+		if err != nil {
+			return nil, err
+		}
+		switch k := key.(type) {
+		case *rsa.PrivateKey:
+			return NewRS256(kid, k), nil
+		case *ecdsa.PrivateKey:
+			return NewES256(kid, k)
+		}
+		return nil, fmt.Errorf("jwt: unsupported private key %T", key)
+	case "PUBLIC KEY":
+		pub, err := x509.ParsePKIXPublicKey(block.Bytes)
+		if err != nil {
+			return nil, err
+		}
+		return NewPublicKey(kid, pub)
+	}
+	return nil, fmt.Errorf("jwt: unsupported PEM block %q", block.Type)
+}
+
+// CanSign reports whether key has private part or secret
+func (k *Key) CanSign() bool {
+	return k.secret != nil || k.private != nil
+}
+
+// Public returns public key, nil for HS256 keys
+func (k *Key) Public() crypto.PublicKey {
+	return k.public
+}
+
+// sign returns signature of data
+func (k *Key) sign(data []byte) ([]byte, error) {
+	if !k.CanSign() {
+		return nil, ErrNoKey
+	}
+	switch k.Alg {
+	case HS256:
+		h := hmac.New(sha256.New, k.secret)
+		h.Write(data)
+		return h.Sum(nil), nil
+	case RS256:
+		sum := sha256.Sum256(data)
+		return k.private.Sign(rand.Reader, sum[:], crypto.SHA256)
+	case ES256:
+		sum := sha256.Sum256(data)
+		r, s, err := ecdsa.Sign(rand.Reader, k.private.(*ecdsa.PrivateKey), sum[:])
+		if err != nil {
+			return nil, err
+		}
+		// JWS signature is r and s as fixed size big endian, not ASN.1
+		sig := make([]byte, 64)
+		r.FillBytes(sig[:32])
+		s.FillBytes(sig[32:])
+		return sig, nil
+	}
+	return nil, ErrAlgorithm
+}
+
+// verify checks signature of data
+func (k *Key) verify(data, sig []byte) error {
+	switch k.Alg {
+	case HS256:
+		if k.secret == nil {
+			return ErrSignature
+		}
+		h := hmac.New(sha256.New, k.secret)
+		h.Write(data)
+		if !hmac.Equal(sig, h.Sum(nil)) {
+			return ErrSignature
+		}
+		return nil
+	case RS256:
+		pub, ok := k.public.(*rsa.PublicKey)
+		if !ok {
+			return ErrSignature
+		}
+		sum := sha256.Sum256(data)
+		if rsa.VerifyPKCS1v15(pub, crypto.SHA256, sum[:], sig) != nil {
+			return ErrSignature
+		}
+		return nil
+	case ES256:
+		pub, ok := k.public.(*ecdsa.PublicKey)
+		if !ok || len(sig) != 64 {
+			return ErrSignature
+		}
+		sum := sha256.Sum256(data)
+		r := new(big.Int).SetBytes(sig[:32])
+		s := new(big.Int).SetBytes(sig[32:])
+		if !ecdsa.Verify(pub, sum[:], r, s) {
+			return ErrSignature
+		}
+		return nil
+	}
+	return ErrAlgorithm
+}
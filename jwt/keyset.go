@@ -0,0 +1,194 @@
+package jwt
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"math/big"
+	"net/http"
+	"sync"
+)
+
+// KeySet holds keys by kid, tokens are signed by current key
+// and verified by key of their kid. On rotation tokens of former key
+// stay valid until they expire or the key is removed:
+//
+//	keys.Rotate(jwt.NewRS256("2024-06", newKey))
+//	// after lifetime of tokens
+//	keys.Remove("2024-01")
+type KeySet struct {
+	lock    sync.RWMutex
+	keys    map[string]*Key
+	order   []string
+	current string
+}
+
+// NewKeySet returns KeySet of keys, the first signing one is current
+func NewKeySet(keys ...*Key) *KeySet {
+	s := &KeySet{keys: make(map[string]*Key)}
+	for _, k := range keys {
+		s.Add(k)
+	}
+	return s
+}
+
+// Add adds key, it becomes current if there is none yet
+// key of same kid is replaced
+func (s *KeySet) Add(k *Key) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	if _, ok := s.keys[k.ID]; !ok {
+		s.order = append(s.order, k.ID)
+	}
+	s.keys[k.ID] = k
+	if s.current == "" && k.CanSign() {
+		s.current = k.ID
+	}
+}
+
+// Rotate adds key and makes it current, former keys still verify
+func (s *KeySet) Rotate(k *Key) {
+	s.Add(k)
+	s.lock.Lock()
+	s.current = k.ID
+	s.lock.Unlock()
+}
+
+// Remove removes key of kid, tokens signed by it aren't valid anymore
+func (s *KeySet) Remove(kid string) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	delete(s.keys, kid)
+	for i, id := range s.order {
+		if id == kid {
+			s.order = append(s.order[:i:i], s.order[i+1:]...)
+			break
+		}
+	}
+	if s.current == kid {
+		s.current = ""
+	}
+}
+
+// Key returns key of kid, nil if unknown
+func (s *KeySet) Key(kid string) *Key {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+	return s.keys[kid]
+}
+
+// Current returns signing key, nil if none
+func (s *KeySet) Current() *Key {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+	return s.keys[s.current]
+}
+
+// jwk JSON Web Key of public RSA or EC key
+type jwk struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid,omitempty"`
+	Use string `json:"use,omitempty"`
+	Alg string `json:"alg,omitempty"`
+	N   string `json:"n,omitempty"`
+	E   string `json:"e,omitempty"`
+	Crv string `json:"crv,omitempty"`
+	X   string `json:"x,omitempty"`
+	Y   string `json:"y,omitempty"`
+}
+
+type jwks struct {
+	Keys []jwk `json:"keys"`
+}
+
+// JWKS returns JSON Web Key Set document of public keys
+// HS256 secrets are never published
+func (s *KeySet) JWKS() ([]byte, error) {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+	doc := jwks{Keys: []jwk{}}
+	for _, kid := range s.order {
+		k := s.keys[kid]
+		switch pub := k.public.(type) {
+		case *rsa.PublicKey:
+			doc.Keys = append(doc.Keys, jwk{
+				Kty: "RSA", Kid: k.ID, Use: "sig", Alg: RS256,
+				N: b64(pub.N.Bytes()),
+				E: b64(big.NewInt(int64(pub.E)).Bytes()),
+			})
+		case *ecdsa.PublicKey:
+			x, y := make([]byte, 32), make([]byte, 32)
+			pub.X.FillBytes(x)
+			pub.Y.FillBytes(y)
+			doc.Keys = append(doc.Keys, jwk{
+				Kty: "EC", Kid: k.ID, Use: "sig", Alg: ES256,
+				Crv: "P-256", X: b64(x), Y: b64(y),
+			})
+		}
+	}
+	return json.Marshal(doc)
+}
+
+// ServeHTTP serves JWKS document e.g. at /.well-known/jwks.json
+func (s *KeySet) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	data, err := s.JWKS()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "public, max-age=300")
+	w.Write(data)
+}
+
+// ParseJWKS returns KeySet verifying tokens by keys of JWKS document
+// e.g. of other token issuer. Keys of unsupported types are skipped
+func ParseJWKS(data []byte) (*KeySet, error) {
+	var doc jwks
+	if err := json.Unmarshal(data, &doc); err != nil {
+		return nil, err
+	}
+	s := NewKeySet()
+	for _, k := range doc.Keys {
+		switch {
+		case k.Kty == "RSA" && (k.Alg == "" || k.Alg == RS256):
+			n, err1 := unb64(k.N)
+			e, err2 := unb64(k.E)
+			if err1 != nil || err2 != nil || len(e) == 0 || len(e) > 4 {
+				return nil, errors.New("jwt: invalid RSA key " + k.Kid)
+			}
+			pub := &rsa.PublicKey{
+				N: new(big.Int).SetBytes(n),
+				E: int(new(big.Int).SetBytes(e).Int64()),
+			}
+			s.Add(&Key{ID: k.Kid, Alg: RS256, public: pub})
+		case k.Kty == "EC" && k.Crv == "P-256":
+			x, err1 := unb64(k.X)
+			y, err2 := unb64(k.Y)
+			if err1 != nil || err2 != nil {
+				return nil, errors.New("jwt: invalid EC key " + k.Kid)
+			}
+			pub := &ecdsa.PublicKey{
+				Curve: elliptic.P256(),
+				X:     new(big.Int).SetBytes(x),
+				Y:     new(big.Int).SetBytes(y),
+			}
+			if !pub.Curve.IsOnCurve(pub.X, pub.Y) {
+				return nil, errors.New("jwt: invalid EC key " + k.Kid)
+			}
+			s.Add(&Key{ID: k.Kid, Alg: ES256, public: pub})
+		}
+	}
+	return s, nil
+}
+
+func b64(b []byte) string {
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+func unb64(s string) ([]byte, error) {
+	return base64.RawURLEncoding.DecodeString(s)
+}
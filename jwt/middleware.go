@@ -0,0 +1,106 @@
+package jwt
+
+import (
+	context2 "context"
+	"net/http"
+	"strings"
+
+	"github.com/mellowarex/gon/auth"
+	"github.com/mellowarex/gon/context"
+)
+
+type claimsKey struct{}
+
+// NewContext returns copy of parent holding claims
+func NewContext(parent context2.Context, claims *Claims) context2.Context {
+	return context2.WithValue(parent, claimsKey{}, claims)
+}
+
+// FromContext returns claims verified by Middleware
+func FromContext(ctx context2.Context) (*Claims, bool) {
+	claims, ok := ctx.Value(claimsKey{}).(*Claims)
+	return claims, ok
+}
+
+// bearerToken returns bearer token of Authorization header of r
+func bearerToken(r *http.Request) string {
+	h := r.Header.Get("Authorization")
+	if len(h) > 7 && strings.EqualFold(h[:7], "Bearer ") {
+		return strings.TrimSpace(h[7:])
+	}
+	return ""
+}
+
+// Middleware verifies bearer tokens of requests and puts their claims
+// in request context, see FromContext. Requests without token pass unchanged,
+// ones with invalid token get 401. Installed with HServer.Use:
+//
+//	gon.GonApp.Use(jwt.Default.Middleware)
+func (m *Manager) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		token := bearerToken(r)
+		if token == "" {
+			next.ServeHTTP(w, r)
+			return
+		}
+		claims, err := m.Verify(token)
+		if err != nil {
+			w.Header().Set("WWW-Authenticate", `Bearer error="invalid_token"`)
+			http.Error(w, http.StatusText(http.StatusUnauthorized), http.StatusUnauthorized)
+			return
+		}
+		next.ServeHTTP(w, r.WithContext(NewContext(r.Context(), claims)))
+	})
+}
+
+// Strategy authenticates requests by bearer token for route guards,
+// claims verified by Middleware are used as they are, or checked again
+// with Validate of Manager if it is set, e.g. for its own iss and aud:
+//
+//	api := mux.PathPrefix("/api").Subrouter().Auth(&jwt.Strategy{}).CSRFExempt()
+type Strategy struct {
+	// Manager verifying tokens, Default if nil
+	Manager *Manager
+	// Load returns user of claims, claims are user if nil
+	Load func(claims *Claims) (interface{}, error)
+}
+
+// Authenticate implements auth.Strategy
+func (s *Strategy) Authenticate(ctx *context.Context) (interface{}, error) {
+	claims, ok := FromContext(ctx.Request.Context())
+	if ok {
+		if s.Manager != nil && s.Manager.Validate(claims) != nil {
+			return nil, auth.ErrInvalidCredentials
+		}
+	} else {
+		token := bearerToken(ctx.Request)
+		if token == "" {
+			return nil, auth.ErrNoCredentials
+		}
+		m := s.Manager
+		if m == nil {
+			m = Default
+		}
+		var err error
+		if claims, err = m.Verify(token); err != nil {
+			return nil, auth.ErrInvalidCredentials
+		}
+	}
+	if s.Load == nil {
+		return claims, nil
+	}
+	user, err := s.Load(claims)
+	if err != nil || user == nil {
+		return nil, auth.ErrInvalidCredentials
+	}
+	return user, nil
+}
+
+// Challenge implements auth.Challenger
+func (s *Strategy) Challenge(ctx *context.Context) {
+	if bearerToken(ctx.Request) != "" {
+		ctx.Output.Header("WWW-Authenticate", `Bearer error="invalid_token"`)
+		return
+	}
+	ctx.Output.Header("WWW-Authenticate", "Bearer")
+}
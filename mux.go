@@ -8,7 +8,9 @@ import (
 	"strings"
 	"reflect"
 
+	"github.com/mellowarex/gon/auth"
 	"github.com/mellowarex/gon/context"
+	"github.com/mellowarex/gon/i18n"
 	"github.com/mellowarex/gon/logs"
 )
 
@@ -66,12 +68,17 @@ func (this *Multiplexer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 		ctrl ControllerInterface
 		err error
 		runMethod string
+		matchReq *http.Request
 	)
 
 	ctx := this.GetContext()
 	ctx.Reset(w, r)
 	defer this.PutContext(ctx)
-	// defer this.conf.RecoverFunc(ctx, this.conf)
+	// Controller.Abort, StopRun and Authorize stop actions by panic,
+	// RecoverFunc turns it into the error response instead of dropping the connection
+	if GConfig.RecoverPanic && GConfig.RecoverFunc != nil {
+		defer GConfig.RecoverFunc(ctx, GConfig)
+	}
 
 	serveStaticRoutes(ctx)
 
@@ -105,7 +112,19 @@ func (this *Multiplexer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 
 	runMethod = r.Method
 
-	if this.Match(r, &match) {
+	// route without locale prefix of url e.g. /fr/posts routes as /posts
+	matchReq = r
+	if GConfig.WebConfig.I18n.I18nOn && GConfig.WebConfig.I18n.URLPrefix {
+		if lang, rest, ok := i18n.Default.SplitPath(r.URL.Path); ok {
+			ctx.Input.SetData(i18n.DataKey, lang)
+			mr, u := *r, *r.URL
+			u.Path, u.RawPath = rest, ""
+			mr.URL = &u
+			matchReq = &mr
+		}
+	}
+
+	if this.Match(matchReq, &match) {
 		ctrl = match.Controller
 		_, ok := match.Route.MethodMapping[runMethod]
 		if match.Route.Mapped && ok {
@@ -125,6 +144,11 @@ func (this *Multiplexer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 		goto Logging
 	}
 
+	// route cache policy, action may override
+	if match.Route != nil && match.Route.cacheControl != "" {
+		ctx.Output.Header("Cache-Control", match.Route.cacheControl)
+	}
+
 	// session init
 	if GConfig.WebConfig.Session.SessionOn {
 		ctx.Input.Cookie, err = GlobalSessions.SessionStart(w, r)
@@ -133,6 +157,44 @@ func (this *Multiplexer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 			exception("503", ctx)
 			goto Logging
 		}
+		defer GlobalSessions.SessionRelease(ctx.Input.Cookie)
+		// persist changed session before response is committed,
+		// store is looked up late as action may regenerate or destroy it
+		ctx.ResponseWriter.BeforeWrite(func() {
+			if err := GlobalSessions.SessionSave(ctx.ResponseWriter, r, ctx.Input.Cookie); err != nil {
+				logs.Error(err)
+			}
+		})
+	}
+
+	// guarded route needs authenticated user
+	if match.Route.authRequired {
+		if _, err := auth.Require(ctx, match.Route.authStrategies...); err != nil {
+			exception("401", ctx)
+			goto Logging
+		}
+	}
+
+	// route and controller method may require roles or permissions
+	if policies := match.Route.policiesFor(runMethod); len(policies) > 0 {
+		if err := auth.Authorize(ctx, policies...); err != nil {
+			if err == auth.ErrForbidden {
+				exception("403", ctx)
+			} else {
+				exception("401", ctx)
+			}
+			goto Logging
+		}
+	}
+
+	// serve from response cache, or record response for it
+	// after guards so cached responses of guarded routes reach allowed users only
+	if match.Route != nil && match.Route.cachePolicy != nil {
+		served, done := serveResponseCache(ctx, match.Route.cachePolicy)
+		if served {
+			goto Logging
+		}
+		defer done()
 	}
 
 	// call controller init func
@@ -144,12 +206,16 @@ func (this *Multiplexer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	// read flash message from cookie then delete
 	ctrl.ReadFlashData()
 
-	// if XSRF is enabled check cookie in request's _csrf
-	if GConfig.WebConfig.EnableXSRF {
-		ctrl.XSRFToken()
-		if r.Method == http.MethodPost || r.Method == http.MethodDelete || r.Method == http.MethodPut ||
-			(r.Method == http.MethodPost && (ctx.Input.Query("_method") == http.MethodDelete || ctx.Input.Query("_method") == http.MethodPut)) {
-			ctrl.CheckXSRFCookie()
+	// if XSRF is enabled check token of unsafe requests unless route is exempt
+	// secret is created only when action renders token, see Controller.XSRFToken
+	if GConfig.WebConfig.EnableXSRF && !match.Route.csrfExempt {
+		if !isSafeMethod(r.Method) {
+			if err := ctx.CheckXSRF(GConfig.WebConfig.XSRFKey, !GConfig.WebConfig.XSRFDisableOriginCheck,
+				GConfig.WebConfig.XSRFTrustedOrigins...); err != nil {
+				logs.Warn("xsrf: %v: %s %s", err, r.Method, r.URL.Path)
+				exception(xsrfStatus(err), ctx)
+				goto Logging
+			}
 		}
 	}
 	// execute action
@@ -191,6 +257,9 @@ func (this *Multiplexer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 
 	ctrl.AfterAction()
 
+	// action may return without writing
+	ctx.ResponseWriter.Commit()
+
 	goto Logging
 
 	Logging:
@@ -212,4 +281,25 @@ func (this *Multiplexer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 		if ctx.Output.Status != 0 {
 			ctx.ResponseWriter.WriteHeader(ctx.Output.Status)
 		}
-}
\ No newline at end of file
+}
+
+// isSafeMethod reports whether method doesn't change state
+// requests of other methods, e.g. POST, PUT, PATCH and DELETE, are xsrf checked
+func isSafeMethod(method string) bool {
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodTrace:
+		return true
+	}
+	return false
+}
+
+// xsrfStatus returns error status of failed xsrf check
+func xsrfStatus(err error) string {
+	switch err {
+	case context.ErrXSRFMissing:
+		return "422"
+	case context.ErrXSRFOrigin:
+		return "403"
+	}
+	return "417"
+}
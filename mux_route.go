@@ -6,6 +6,8 @@ import (
 	"strings"
 	"fmt"
 	"errors"
+
+	"github.com/mellowarex/gon/auth"
 )
 
 var (
@@ -93,6 +95,51 @@ func (r *Route) Subrouter() *Multiplexer {
 	return router
 }
 
+// CSRFExempt turns off xsrf checks of routes of router
+// including routes added later, e.g. for a group of API routes:
+//
+//     api := r.PathPrefix("/api").Subrouter().CSRFExempt()
+func (r *Multiplexer) CSRFExempt() *Multiplexer {
+	r.csrfExempt = true
+	for _, route := range r.routes {
+		route.CSRFExempt()
+	}
+	return r
+}
+
+// Auth requires authentication of routes of router
+// including routes added later, see Route.Auth:
+//
+//     admin := r.PathPrefix("/admin").Subrouter().Auth()
+func (r *Multiplexer) Auth(strategies ...auth.Strategy) *Multiplexer {
+	r.authRequired = true
+	r.authStrategies = strategies
+	for _, route := range r.routes {
+		route.Auth(strategies...)
+	}
+	return r
+}
+
+// Roles requires one of roles for routes of router
+// including routes added later, see Route.Roles
+func (r *Multiplexer) Roles(roles ...string) *Multiplexer {
+	return r.requirePolicy(auth.Policy{Roles: roles})
+}
+
+// Permissions requires all of permissions for routes of router
+// including routes added later, see Route.Permissions
+func (r *Multiplexer) Permissions(permissions ...string) *Multiplexer {
+	return r.requirePolicy(auth.Policy{Permissions: permissions})
+}
+
+func (r *Multiplexer) requirePolicy(p auth.Policy) *Multiplexer {
+	r.policies = appendPolicy(r.policies, p)
+	for _, route := range r.routes {
+		route.requirePolicy(p)
+	}
+	return r
+}
+
 // Host registers a new route with a matcher for the URL host.
 // See Route.Host().
 func (r *Multiplexer) Host(tpl string) *Route {
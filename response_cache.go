@@ -0,0 +1,178 @@
+package gon
+
+import (
+	"bytes"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/mellowarex/gon/cache"
+	"github.com/mellowarex/gon/context"
+	"github.com/mellowarex/gon/logs"
+)
+
+// responseRecorder copies response written by controller
+// so it can be stored in ResponseCache
+type responseRecorder struct {
+	http.ResponseWriter
+	status int
+	body   bytes.Buffer
+}
+
+func (this *responseRecorder) WriteHeader(code int) {
+	if this.status == 0 {
+		this.status = code
+	}
+	this.ResponseWriter.WriteHeader(code)
+}
+
+func (this *responseRecorder) Write(p []byte) (int, error) {
+	if this.status == 0 {
+		this.status = http.StatusOK
+	}
+	this.body.Write(p)
+	return this.ResponseWriter.Write(p)
+}
+
+// Flush http.Flusher
+func (this *responseRecorder) Flush() {
+	if f, ok := this.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+// serveResponseCache serves request from ResponseCache if cached.
+// if not, done must be called after action is executed
+// to store the response for next requests
+func serveResponseCache(ctx *context.Context, policy *cache.Policy) (served bool, done func()) {
+	done = func() {}
+	if ResponseCache == nil || (!ctx.Input.IsGet() && !ctx.Input.IsHead()) {
+		return false, done
+	}
+	baseKey := policy.Key(ctx.Request)
+	key := baseKey
+	// response varies on other request headers, extend key with their values
+	if v, ok := ResponseCache.Get("vary:" + baseKey); ok {
+		key = varyKey(baseKey, v.Vary, ctx.Request)
+	}
+	if e, ok := ResponseCache.Get(key); ok {
+		writeCachedResponse(ctx, e)
+		return true, done
+	}
+
+	e, leader := ResponseCache.Lead(key)
+	if !leader {
+		if e != nil && len(e.Vary) > 0 {
+			// leader response varies, serve it to requests of same variant only
+			if vkey := varyKey(baseKey, e.Vary, ctx.Request); vkey != key {
+				e, _ = ResponseCache.Get(vkey)
+			}
+		}
+		if e != nil {
+			writeCachedResponse(ctx, e)
+			return true, done
+		}
+		// leader response was not cacheable or is other variant, run action
+		return false, done
+	}
+
+	rec := &responseRecorder{ResponseWriter: ctx.ResponseWriter.ResponseWriter}
+	ctx.ResponseWriter.ResponseWriter = rec
+	return false, func() {
+		ctx.ResponseWriter.ResponseWriter = rec.ResponseWriter
+		e := newCacheEntry(ctx, rec, policy)
+		if e == nil || len(e.Vary) == 0 {
+			ResponseCache.Done(key, e)
+			return
+		}
+		// first store of response with Vary, key it by vary headers
+		vkey := varyKey(baseKey, e.Vary, ctx.Request)
+		ResponseCache.Set("vary:"+baseKey, &cache.Entry{Vary: e.Vary, Tags: e.Tags, Expires: e.Expires})
+		if vkey != key {
+			// waiters of key get e if their variant is vkey
+			ResponseCache.Set(vkey, e)
+			ResponseCache.Share(key, e)
+			return
+		}
+		ResponseCache.Done(key, e)
+	}
+}
+
+// newCacheEntry builds cache entry from recorded response
+// returns nil if response must not be cached
+func newCacheEntry(ctx *context.Context, rec *responseRecorder, policy *cache.Policy) *cache.Entry {
+	// xsrf token is bound to secret of user
+	if rec.status != http.StatusOK || ctx.XSRFTokenUsed() {
+		return nil
+	}
+	header := ctx.ResponseWriter.Header()
+	if header.Get("Set-Cookie") != "" {
+		return nil
+	}
+	cc := strings.ToLower(header.Get("Cache-Control"))
+	if strings.Contains(cc, "no-store") || strings.Contains(cc, "private") {
+		return nil
+	}
+	var vary []string
+	for _, v := range header.Values("Vary") {
+		for _, h := range strings.Split(v, ",") {
+			if h = strings.TrimSpace(h); h == "*" {
+				return nil
+			} else if h != "" {
+				vary = append(vary, http.CanonicalHeaderKey(h))
+			}
+		}
+	}
+	now := time.Now()
+	e := &cache.Entry{
+		Status:  rec.status,
+		Header:  header.Clone(),
+		Body:    append([]byte(nil), rec.body.Bytes()...),
+		Tags:    append(append([]string{}, policy.Tags...), cache.Tags(ctx)...),
+		Vary:    vary,
+		Created: now,
+	}
+	if policy.TTL > 0 {
+		e.Expires = now.Add(policy.TTL)
+	}
+	return e
+}
+
+func varyKey(baseKey string, vary []string, r *http.Request) string {
+	key := baseKey
+	for _, h := range vary {
+		key += "\n" + h + ":" + r.Header.Get(h)
+	}
+	return key
+}
+
+func writeCachedResponse(ctx *context.Context, e *cache.Entry) {
+	header := ctx.ResponseWriter.Header()
+	for k, v := range e.Header {
+		header[k] = append([]string(nil), v...)
+	}
+	header.Set("Age", strconv.Itoa(int(time.Since(e.Created).Seconds())))
+	if ctx.Output.NotModified() {
+		return
+	}
+	ctx.ResponseWriter.WriteHeader(e.Status)
+	if ctx.Input.IsHead() {
+		return
+	}
+	if _, err := ctx.ResponseWriter.Write(e.Body); err != nil {
+		logs.Error(err)
+	}
+}
+
+// registerResponseCache sets up default in memory ResponseCache
+func registerResponseCache() error {
+	if ResponseCache == nil {
+		size := GConfig.ResponseCacheNum
+		if size < 1 {
+			size = 1024
+		}
+		ResponseCache = cache.New(cache.NewMemoryStore(size))
+	}
+	return nil
+}
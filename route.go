@@ -4,6 +4,9 @@ import (
 	"fmt"
 	"net/http"
 	"strings"
+
+	"github.com/mellowarex/gon/auth"
+	"github.com/mellowarex/gon/cache"
 )
 
 // Route stores information to match a request
@@ -20,6 +23,15 @@ type Route struct {
 	// Error resulted from building a route.
 	err error
 
+	// Cache-Control header sent for responses of route
+	cacheControl string
+
+	// server side response cache policy of route
+	cachePolicy *cache.Policy
+
+	// roles and permissions required by controller methods of route
+	methodPolicies map[string][]auth.Policy
+
 	routeConf
 }
 
@@ -39,6 +51,17 @@ type routeConf struct {
 	// List of matchers
 	matchers []matcher
 
+	// If true, xsrf token isn't checked for requests of route
+	csrfExempt bool
+
+	// If true, requests of route must be authenticated by authStrategies,
+	// auth.Default strategies if empty
+	authRequired   bool
+	authStrategies []auth.Strategy
+
+	// Roles and permissions required by route and its group
+	policies []auth.Policy
+
 	// Manager for the variables from host and path.
 	regexp routeRegexpGroup
 }
@@ -224,6 +247,146 @@ func (r *Route) Controller(ctrl ControllerInterface) *Route {
 
 
 
+// CacheControl sets default Cache-Control policy for responses of route
+// controller actions can still override it with Ctx.Output.CacheControl
+//
+//     r.Route("/about", &AboutController{}).CacheControl("public", "max-age=600")
+func (r *Route) CacheControl(directives ...string) *Route {
+	if r.err == nil {
+		r.cacheControl = strings.Join(directives, ", ")
+	}
+	return r
+}
+
+// Cache enables server side response cache for route
+// GET and HEAD responses with status 200 are stored in ResponseCache
+// unless they set cookies, Cache-Control no-store/private or render xsrf token.
+// Auth, Roles and Permissions of route are checked before cached responses
+// are served, which are shared by all allowed users: responses depending
+// on user must set Cache-Control private or vary by Policy.Headers.
+// With i18n on, responses vary on Accept-Language and, when locale may come
+// from lang cookie or session, on Cookie so they are cached per client;
+// routes shared by all clients of a locale should take it from url prefix
+//
+//     r.Route("/posts", &PostsController{}).Cache(cache.Policy{TTL: time.Minute})
+func (r *Route) Cache(policy cache.Policy) *Route {
+	if r.err == nil {
+		r.cachePolicy = &policy
+	}
+	return r
+}
+
+// CSRFExempt turns off xsrf checks of route and of its subrouter routes
+// e.g. for webhooks and token authenticated APIs
+//
+//     r.Route("/hooks/stripe", &StripeController{}).CSRFExempt()
+func (r *Route) CSRFExempt() *Route {
+	if r.err == nil {
+		r.csrfExempt = true
+		for _, m := range r.matchers {
+			if sub, ok := m.(*Multiplexer); ok {
+				sub.CSRFExempt()
+			}
+		}
+	}
+	return r
+}
+
+// Auth requires requests of route and of its subrouter routes
+// to be authenticated by strategies, auth.Default ones if none given.
+// Unauthenticated requests get 401 of ErrorMaps
+//
+//     r.Route("/account", &AccountController{}).Auth()
+//     r.Route("/feed", &FeedController{}).Auth(&auth.APIKeyStrategy{Validate: clientOfKey})
+func (r *Route) Auth(strategies ...auth.Strategy) *Route {
+	if r.err == nil {
+		r.authRequired = true
+		r.authStrategies = strategies
+		for _, m := range r.matchers {
+			if sub, ok := m.(*Multiplexer); ok {
+				sub.Auth(strategies...)
+			}
+		}
+	}
+	return r
+}
+
+// Roles allows requests of route and of its subrouter routes
+// only to users having one of roles, others get 403 of ErrorMaps
+// and anonymous users 401. Each call adds a requirement:
+//
+//     r.PathPrefix("/admin").Subrouter().Roles("admin")
+func (r *Route) Roles(roles ...string) *Route {
+	return r.requirePolicy(auth.Policy{Roles: roles})
+}
+
+// Permissions allows requests of route and of its subrouter routes
+// only to users having all of permissions, see Roles
+func (r *Route) Permissions(permissions ...string) *Route {
+	return r.requirePolicy(auth.Policy{Permissions: permissions})
+}
+
+// MethodRoles requires one of roles for controller method of route
+// method is http method e.g. "POST" or method mapped by Multiplexer.Router:
+//
+//     r.Router("/api/food", &FoodController{}, "get:ListFood;delete:DeleteFood").
+//         MethodRoles("DeleteFood", "admin")
+func (r *Route) MethodRoles(method string, roles ...string) *Route {
+	return r.requireMethodPolicy(method, auth.Policy{Roles: roles})
+}
+
+// MethodPermissions requires all of permissions for controller method of route
+// see MethodRoles
+func (r *Route) MethodPermissions(method string, permissions ...string) *Route {
+	return r.requireMethodPolicy(method, auth.Policy{Permissions: permissions})
+}
+
+func (r *Route) requirePolicy(p auth.Policy) *Route {
+	if r.err == nil {
+		r.policies = appendPolicy(r.policies, p)
+		for _, m := range r.matchers {
+			if sub, ok := m.(*Multiplexer); ok {
+				sub.requirePolicy(p)
+			}
+		}
+	}
+	return r
+}
+
+func (r *Route) requireMethodPolicy(method string, p auth.Policy) *Route {
+	if r.err == nil {
+		if r.methodPolicies == nil {
+			r.methodPolicies = make(map[string][]auth.Policy)
+		}
+		method = policyMethod(method)
+		r.methodPolicies[method] = append(r.methodPolicies[method], p)
+	}
+	return r
+}
+
+// policiesFor returns policies of route and of its controller method
+func (r *Route) policiesFor(method string) []auth.Policy {
+	mp := r.methodPolicies[policyMethod(method)]
+	if len(mp) == 0 {
+		return r.policies
+	}
+	return append(r.policies[:len(r.policies):len(r.policies)], mp...)
+}
+
+// policyMethod returns key of method policies, http methods are upper case
+func policyMethod(method string) string {
+	if m := strings.ToUpper(method); HTTPMETHOD[m] {
+		return m
+	}
+	return method
+}
+
+// appendPolicy appends p to copy of policies,
+// route configs copied from group share their policies
+func appendPolicy(policies []auth.Policy, p auth.Policy) []auth.Policy {
+	return append(policies[:len(policies):len(policies)], p)
+}
+
 // Host adds a matcher for the URL host.
 // It accepts a template with zero or more URL variables enclosed by {}.
 // Variables can define an optional regexp pattern to be matched:
@@ -15,6 +15,7 @@ type Cookie struct{
 	sid   string
 	values map[string]interface{}
 	lock sync.RWMutex
+	legacy bool // read from cookie encoded before keyring
 }
 
 // Set value to cookie session
@@ -28,20 +29,13 @@ func (this *Cookie) Set(ctx context.Context, key string, value interface{},r *ht
 }
 // Save Write cookie session to http response cookie
 func (this *Cookie) Save(ctx context.Context,rr interface{} ,r *http.Request, w http.ResponseWriter) error {
-	encodedCookie, err := encodeCookie(cookieProvide.block, cookieProvide.config.SecurityKey, cookieProvide.config.SecurityName, this.values)
+	encodedCookie, err := encodeCookie(cookieProvide.ring, cookieProvide.config.SecurityName, this.values)
 	fmt.Println("saving: ",this.values)
 	fmt.Println("cookie: ",encodedCookie)
 	if err == nil {
-		cookie := &http.Cookie{Name: cookieProvide.config.CookieName,
-			Value:    url.QueryEscape(encodedCookie),
-			Path:     "/",
-			HttpOnly: true,
-			Secure:   cookieProvide.config.Secure,
-			MaxAge:   cookieProvide.config.Maxage,
-			Domain:   "",
-		}
+		cookie := cookieProvide.cookie(url.QueryEscape(encodedCookie))
 		http.SetCookie(w, cookie)
-		r.AddCookie(cookie)
+		setRequestCookie(r, cookie)
 	}
 	return nil
 }
@@ -72,6 +66,11 @@ func (this *Cookie) Flush(ctx context.Context,r *http.Request, w http.ResponseWr
 	return nil
 }
 
+// stale reports cookie must be re-issued in keyring format
+func (this *Cookie) stale() bool {
+	return this.legacy
+}
+
 // SessionID Return id of this cookie session
 func (this *Cookie) SessionID(context.Context) string {
 	return this.sid
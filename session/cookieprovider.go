@@ -4,65 +4,124 @@ import (
 	"context"
 	"crypto/aes"
 	"crypto/cipher"
+	"encoding/hex"
+	"net/http"
+
+	"github.com/mellowarex/gon/logs"
 	"github.com/mellowarex/gon/utils"
 )
 
+// CookieKey one key of cookie session keyring
+type CookieKey struct {
+	ID       string `json:"id"`       // embedded in cookie to select key when decoding
+	HashKey  string `json:"hashKey"`  // secret mixed into encryption key
+	BlockKey string `json:"blockKey"` // encryption secret
+}
+
 type cookieConfig struct {
-	SecurityKey  string `json:"securityKey"`
-	BlockKey     string `json:"blockKey"`
-	SecurityName string `json:"securityName"`
-	CookieName   string `json:"cookieName"`
-	Secure       bool   `json:"secure"`
-	Maxage       int    `json:"maxage"`
+	Keys         []CookieKey `json:"keys"`
+	SecurityKey  string      `json:"securityKey"`
+	BlockKey     string      `json:"blockKey"`
+	SecurityName string      `json:"securityName"`
+	CookieName   string      `json:"cookieName"`
+	Secure       bool        `json:"secure"`
+	Maxage       int         `json:"maxage"`
 }
 
 // CookieProvider Cookie session provider
 type CookieProvider struct {
 	maxlifetime int64
 	config      *cookieConfig
-	block       cipher.Block
+	manager     *CookieConfig
+	ring        *keyring
+	legacy      cipher.Block // opens cookies sealed before keyring, nil if blockKey is no AES key
+}
+
+// setConfig keep manager config for cookie attributes
+func (pder *CookieProvider) setConfig(cf *CookieConfig) {
+	pder.manager = cf
+}
+
+// cookie returns session cookie holding value
+// with attributes of manager config
+func (pder *CookieProvider) cookie(value string) *http.Cookie {
+	cf := CookieConfig{}
+	if pder.manager != nil {
+		cf = *pder.manager
+	}
+	cf.CookieName = pder.config.CookieName
+	if pder.config.Maxage != 0 {
+		cf.CookieLifeTime = pder.config.Maxage
+	}
+	return cf.cookie(value, pder.config.Secure || cf.Secure)
 }
 
 // SessionInit Init cookie session provider with max lifetime and config json.
 // maxlifetime is ignored.
 // json config:
-// 	securityKey - hash string
-// 	blockKey - gob encode hash string. it's saved as aes crypto.
-// 	securityName - recognized name in encoded cookie string
+// 	keys - keyring, newest first: [{"id": "2", "hashKey": "...", "blockKey": "..."}, ...]
+// 	       cookies are encrypted with the first key and decrypted with the key of their id
+// 	securityKey - hash string, oldest key of keyring with id "0"
+// 	blockKey - encryption string, oldest key of keyring with id "0"
+// 	securityName - recognized name in encoded cookie string, cookieName by default
 // 	cookieName - cookie name
 // 	maxage - cookie max life time.
+// Without keys a random key is generated, sessions are then lost on restart.
+// Cookies encoded before keyring with securityKey and blockKey are still read
+// when securityName is configured, and are re-issued in keyring format
+// when the session is saved.
 func (pder *CookieProvider) SessionInit(ctx context.Context, maxlifetime int64, configFile string) error {
 	pder.config = &cookieConfig{}
-	// fmt.Println(configFile)
 	err := utils.ParseConfigFile(configFile, pder.config)
 	if err != nil {
 		return err
 	}
-	if pder.config.BlockKey == "" {
-		pder.config.BlockKey = string(generateRandomKey(16))
+	keys := pder.config.Keys
+	if pder.config.SecurityKey != "" || pder.config.BlockKey != "" {
+		keys = append(keys, CookieKey{ID: "0", HashKey: pder.config.SecurityKey, BlockKey: pder.config.BlockKey})
+	}
+	if len(keys) == 0 {
+		logs.Warn("session: no cookie session keys in " + configFile + ", using generated key; sessions will not survive restart")
+		keys = []CookieKey{{
+			ID:       "gen",
+			HashKey:  hex.EncodeToString(generateRandomKey(32)),
+			BlockKey: hex.EncodeToString(generateRandomKey(32)),
+		}}
+	}
+	if pder.config.CookieName == "" && pder.manager != nil {
+		pder.config.CookieName = pder.manager.CookieName
 	}
 	if pder.config.SecurityName == "" {
-		pder.config.SecurityName = string(generateRandomKey(20))
+		pder.config.SecurityName = pder.config.CookieName
 	}
-	pder.block, err = aes.NewCipher([]byte(pder.config.BlockKey))
-	if err != nil {
+	if pder.ring, err = newKeyring(keys); err != nil {
 		return err
 	}
+	pder.legacy = nil
+	if pder.config.SecurityKey != "" && pder.config.BlockKey != "" {
+		pder.legacy, _ = aes.NewCipher([]byte(pder.config.BlockKey))
+	}
 	pder.maxlifetime = maxlifetime
 	return nil
 }
 
 // SessionRead Get SessionStore in cooke.
 // decode cooke string to map and put into SessionStore with sid.
-func (pder *CookieProvider) SessionRead(ctx context.Context, sid string) (*Cookie, error) {
-	maps, _ := decodeCookie(pder.block,
-		pder.config.SecurityKey,
+// legacy cookie is decoded once and marked to be re-issued
+func (pder *CookieProvider) SessionRead(ctx context.Context, sid string) (Store, error) {
+	maps, err := decodeCookie(pder.ring,
 		pder.config.SecurityName,
 		sid, pder.maxlifetime)
+	legacy := false
+	if err != nil && pder.legacy != nil {
+		maps, err = decodeLegacyCookie(pder.legacy, pder.config.SecurityKey,
+			pder.config.SecurityName, sid, pder.maxlifetime)
+		legacy = err == nil
+	}
 	if maps == nil {
 		maps = make(map[string]interface{})
 	}
-	rs := &Cookie{sid: sid, values: maps}
+	rs := &Cookie{sid: sid, values: maps, legacy: legacy}
 	return rs, nil
 }
 
@@ -71,9 +130,10 @@ func (pder *CookieProvider) SessionExist(ctx context.Context, sid string) (bool,
 	return true, nil
 }
 
-// SessionRegenerate Implement method, no used.
-func (pder *CookieProvider) SessionRegenerate(ctx context.Context, oldsid, sid string) (*Cookie, error) {
-	return &Cookie{}, nil
+// SessionRegenerate keep values of old cookie,
+// cookie value is sealed again with fresh nonce on Save
+func (pder *CookieProvider) SessionRegenerate(ctx context.Context, oldsid, sid string) (Store, error) {
+	return pder.SessionRead(ctx, oldsid)
 }
 
 // SessionDestroy Implement method, no used.
@@ -93,4 +153,13 @@ func (pder *CookieProvider) SessionAll(context.Context) int {
 // SessionUpdate Implement method, no used.
 func (pder *CookieProvider) SessionUpdate(ctx context.Context, sid string) error {
 	return nil
+}
+
+// sessionID cookie session id is the encoded cookie itself
+func (pder *CookieProvider) sessionID() (string, error) {
+	return encodeCookie(pder.ring, pder.config.SecurityName, make(map[string]interface{}))
+}
+
+func init() {
+	Register("cookie", cookieProvide)
 }
\ No newline at end of file
@@ -0,0 +1,356 @@
+package session
+
+import (
+	"context"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+var fileProvider = &FileProvider{}
+
+// FileStore File session store
+// values are kept in memory and written to file on Save
+type FileStore struct {
+	sid      string
+	provider *FileProvider
+	lock     sync.RWMutex
+	values   map[string]interface{}
+}
+
+// Set value to file session
+func (fs *FileStore) Set(ctx context.Context, key string, value interface{}, r *http.Request, w http.ResponseWriter) error {
+	fs.lock.Lock()
+	defer fs.lock.Unlock()
+	fs.values[key] = value
+	return nil
+}
+
+// Get value from file session
+func (fs *FileStore) Get(ctx context.Context, key string) interface{} {
+	fs.lock.RLock()
+	defer fs.lock.RUnlock()
+	if v, ok := fs.values[key]; ok {
+		return v
+	}
+	return nil
+}
+
+// Delete value in file session by given key
+func (fs *FileStore) Delete(ctx context.Context, key string, r *http.Request, w http.ResponseWriter) error {
+	fs.lock.Lock()
+	defer fs.lock.Unlock()
+	delete(fs.values, key)
+	return nil
+}
+
+// Flush Clean all values in file session
+func (fs *FileStore) Flush(ctx context.Context, r *http.Request, w http.ResponseWriter) error {
+	fs.lock.Lock()
+	defer fs.lock.Unlock()
+	fs.values = make(map[string]interface{})
+	return nil
+}
+
+// SessionID Get file session store id
+func (fs *FileStore) SessionID(context.Context) string {
+	return fs.sid
+}
+
+// Save writes file session values to its file
+func (fs *FileStore) Save(ctx context.Context, rr interface{}, r *http.Request, w http.ResponseWriter) error {
+	fs.lock.RLock()
+	b, err := EncodeGob(fs.values)
+	fs.lock.RUnlock()
+	if err != nil {
+		return err
+	}
+	return fs.provider.write(fs.sid, b)
+}
+
+// FileProvider File session provider
+// each session is a gob file under savePath/x/y/sid,
+// sharded by the first two characters of sid.
+// Files are replaced by atomic rename and concurrent writers,
+// in this or other gon processes, are serialized with flock
+// on a sibling ".lock" file, see file_lock_*.go. Expired sessions are removed by mtime.
+type FileProvider struct {
+	maxlifetime int64
+	savePath    string
+}
+
+// SessionInit Init file session provider.
+// savePath is the directory for session files, default is os temp dir
+func (fp *FileProvider) SessionInit(ctx context.Context, maxlifetime int64, savePath string) error {
+	if savePath == "" {
+		savePath = filepath.Join(os.TempDir(), "gonsession")
+	}
+	if err := os.MkdirAll(savePath, 0700); err != nil {
+		return err
+	}
+	fp.maxlifetime = maxlifetime
+	fp.savePath = savePath
+	return nil
+}
+
+// path returns file path of session sid
+func (fp *FileProvider) path(sid string) (string, error) {
+	if len(sid) < 2 || strings.ContainsAny(sid, "/\\\x00") || strings.HasPrefix(sid, ".") {
+		return "", errors.New("session: invalid session id for file provider")
+	}
+	return filepath.Join(fp.savePath, string(sid[0]), string(sid[1]), sid), nil
+}
+
+// lockSession takes exclusive flock of sid's lock file
+// returned func releases it
+func (fp *FileProvider) lockSession(path string) (func(), error) {
+	return flock(context.Background(), path+".lock")
+}
+
+// SessionLock lock file session sid for a request until returned func is called
+// it uses its own lock file so provider writes under lockSession do not block on it
+func (fp *FileProvider) SessionLock(ctx context.Context, sid string) (func(), error) {
+	path, err := fp.path(sid)
+	if err != nil {
+		return nil, err
+	}
+	return flock(ctx, path+".req.lock")
+}
+
+// flock takes exclusive lock of lockPath, polling until ctx is done
+// a lock file removed by SessionGC while waiting is opened again,
+// so holders always lock the file at lockPath
+func flock(ctx context.Context, lockPath string) (func(), error) {
+	if err := os.MkdirAll(filepath.Dir(lockPath), 0700); err != nil {
+		return nil, err
+	}
+	for {
+		f, err := os.OpenFile(lockPath, os.O_RDWR|os.O_CREATE, 0600)
+		if err != nil {
+			return nil, err
+		}
+		locked, err := tryLockFile(f)
+		if err != nil {
+			f.Close()
+			return nil, err
+		}
+		if locked {
+			fi, err1 := f.Stat()
+			pi, err2 := os.Stat(lockPath)
+			if err1 == nil && err2 == nil && os.SameFile(fi, pi) {
+				return func() {
+					unlockFile(f)
+					f.Close()
+				}, nil
+			}
+			// lock file was unlinked before we locked it, lock the new one
+			unlockFile(f)
+			f.Close()
+			continue
+		}
+		f.Close()
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(10 * time.Millisecond):
+		}
+	}
+}
+
+// tryFlock takes lock of lockPath if it is free
+func tryFlock(lockPath string) (func(), bool) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	unlock, err := flock(ctx, lockPath)
+	return unlock, err == nil
+}
+
+// write replaces session file content atomically
+func (fp *FileProvider) write(sid string, b []byte) error {
+	path, err := fp.path(sid)
+	if err != nil {
+		return err
+	}
+	unlock, err := fp.lockSession(path)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+	tmp, err := ioutil.TempFile(filepath.Dir(path), "."+sid+".tmp-")
+	if err != nil {
+		return err
+	}
+	if _, err = tmp.Write(b); err == nil {
+		err = tmp.Sync()
+	}
+	if cerr := tmp.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+// read returns decoded values of session file
+// missing file returns empty values
+func (fp *FileProvider) read(path string) (map[string]interface{}, error) {
+	b, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) || len(b) == 0 {
+		return make(map[string]interface{}), nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return DecodeGob(b)
+}
+
+// SessionRead Read file session by sid.
+// if file is not exist, session is empty and its file is written on save.
+// reading refreshes file mtime so active sessions are not collected
+func (fp *FileProvider) SessionRead(ctx context.Context, sid string) (Store, error) {
+	path, err := fp.path(sid)
+	if err != nil {
+		return nil, err
+	}
+	values, err := fp.read(path)
+	if err != nil {
+		return nil, err
+	}
+	if _, err = os.Stat(path); err == nil {
+		now := time.Now()
+		os.Chtimes(path, now, now)
+	}
+	return &FileStore{sid: sid, provider: fp, values: values}, nil
+}
+
+// SessionExist Check file session exist.
+// it checks the file named from sid exist or not.
+func (fp *FileProvider) SessionExist(ctx context.Context, sid string) (bool, error) {
+	path, err := fp.path(sid)
+	if err != nil {
+		return false, err
+	}
+	_, err = os.Stat(path)
+	return err == nil, nil
+}
+
+// SessionDestroy Remove all files in this save path
+func (fp *FileProvider) SessionDestroy(ctx context.Context, sid string) error {
+	path, err := fp.path(sid)
+	if err != nil {
+		return err
+	}
+	unlock, err := fp.lockSession(path)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+	// lock files stay, request of session may hold its lock, SessionGC removes them
+	os.Remove(path)
+	return nil
+}
+
+// SessionGC Recycle files in save path
+// sessions whose file mtime is older than maxlifetime are removed.
+// Lock files are removed with expired sessions and when left by destroyed
+// or regenerated ones, only while held so no request is locking them
+func (fp *FileProvider) SessionGC(context.Context) {
+	deadline := time.Now().Add(-time.Duration(fp.maxlifetime) * time.Second)
+	filepath.Walk(fp.savePath, func(path string, f os.FileInfo, err error) error {
+		if err != nil || f.IsDir() || !f.ModTime().Before(deadline) {
+			return nil
+		}
+		switch {
+		case strings.HasPrefix(f.Name(), "."):
+			// temp file of failed write
+			os.Remove(path)
+		case strings.HasSuffix(path, ".req.lock"):
+			fp.removeLocks(strings.TrimSuffix(path, ".req.lock"), deadline)
+		case strings.HasSuffix(path, ".lock"):
+			fp.removeLocks(strings.TrimSuffix(path, ".lock"), deadline)
+		default:
+			fp.removeLocks(path, deadline)
+		}
+		return nil
+	})
+}
+
+// removeLocks removes session file of path if expired and its lock files
+// sessions locked by requests or writes are skipped
+func (fp *FileProvider) removeLocks(path string, deadline time.Time) {
+	unlockReq, ok := tryFlock(path + ".req.lock")
+	if !ok {
+		return
+	}
+	defer unlockReq()
+	unlock, ok := tryFlock(path + ".lock")
+	if !ok {
+		return
+	}
+	defer unlock()
+	if fi, err := os.Stat(path); err == nil {
+		if !fi.ModTime().Before(deadline) {
+			return
+		}
+		os.Remove(path)
+	}
+	os.Remove(path + ".lock")
+	os.Remove(path + ".req.lock")
+}
+
+// SessionAll Get active file session number.
+// it walks save path to count files.
+func (fp *FileProvider) SessionAll(context.Context) int {
+	count := 0
+	filepath.Walk(fp.savePath, func(path string, f os.FileInfo, err error) error {
+		if err != nil || f.IsDir() || strings.HasSuffix(path, ".lock") || strings.HasPrefix(f.Name(), ".") {
+			return nil
+		}
+		count++
+		return nil
+	})
+	return count
+}
+
+// SessionRegenerate Generate new sid for file session.
+// old session file is renamed to the new sid under its lock,
+// so data moves in one step. if old file is missing, new session is empty
+func (fp *FileProvider) SessionRegenerate(ctx context.Context, oldsid, sid string) (Store, error) {
+	oldPath, err := fp.path(oldsid)
+	if err != nil {
+		return nil, err
+	}
+	newPath, err := fp.path(sid)
+	if err != nil {
+		return nil, err
+	}
+	if _, err = os.Stat(newPath); err == nil {
+		return nil, errors.New("session: newsid " + sid + " exist")
+	}
+	if err = os.MkdirAll(filepath.Dir(newPath), 0700); err != nil {
+		return nil, err
+	}
+
+	unlock, err := fp.lockSession(oldPath)
+	if err != nil {
+		return nil, err
+	}
+	// lock files of old sid stay until SessionGC, request may hold them
+	err = os.Rename(oldPath, newPath)
+	unlock()
+	if err != nil && !os.IsNotExist(err) {
+		return nil, err
+	}
+	return fp.SessionRead(ctx, sid)
+}
+
+func init() {
+	Register("file", fileProvider)
+}
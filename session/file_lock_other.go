@@ -0,0 +1,36 @@
+//go:build !darwin && !dragonfly && !freebsd && !linux && !netbsd && !openbsd && !windows
+// +build !darwin,!dragonfly,!freebsd,!linux,!netbsd,!openbsd,!windows
+
+package session
+
+import (
+	"os"
+	"sync"
+)
+
+// platforms without file locks serialize sessions of this process only
+var (
+	fileLocksMu sync.Mutex
+	fileLocks   = make(map[string]*os.File)
+)
+
+// tryLockFile locks name of f in process without blocking
+func tryLockFile(f *os.File) (bool, error) {
+	fileLocksMu.Lock()
+	defer fileLocksMu.Unlock()
+	if _, ok := fileLocks[f.Name()]; ok {
+		return false, nil
+	}
+	fileLocks[f.Name()] = f
+	return true, nil
+}
+
+// unlockFile releases lock of f
+func unlockFile(f *os.File) error {
+	fileLocksMu.Lock()
+	defer fileLocksMu.Unlock()
+	if fileLocks[f.Name()] == f {
+		delete(fileLocks, f.Name())
+	}
+	return nil
+}
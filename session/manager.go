@@ -1,22 +1,38 @@
 package session
 
 import (
+	"context"
+	"encoding/hex"
 	"errors"
 	"fmt"
 	"net/http"
 	"net/textproto"
 	"net/url"
+	"strings"
 	"time"
 )
 
-// Manager contains cookie and its configurations
+// Manager contains session provider and its configurations
 type Manager struct{
-	provider *CookieProvider
+	provider Provider
 	config *CookieConfig
 }
 
+// idGenerator is implemented by providers which define
+// their own session id format
+type idGenerator interface {
+	sessionID() (string, error)
+}
+
+// configReceiver is implemented by providers which need
+// manager config before SessionInit, e.g. for SessionIDPrefix
+type configReceiver interface {
+	setConfig(cf *CookieConfig)
+}
+
 // CookieConfig define the session config
 type CookieConfig struct {
+	ProviderName            string `json:"providerName"`
 	CookieName              string `json:"cookieName"`
 	EnableSetCookie         bool   `json:"enableSetCookie,omitempty"`
 	Gclifetime              int64  `json:"gclifetime"`
@@ -31,22 +47,28 @@ type CookieConfig struct {
 	SessionNameInHTTPHeader string `json:"SessionNameInHTTPHeader"`
 	EnableSidInURLQuery     bool   `json:"EnableSidInURLQuery"`
 	SessionIDPrefix         string `json:"sessionIDPrefix"`
+	Path                    string `json:"path"`
+	SameSite                http.SameSite `json:"sameSite"`
+	EnableLock              bool  `json:"enableLock"`  // lock session for whole request when provider is a Locker
+	LockTimeout             int64 `json:"lockTimeout"` // lock wait in milliseconds, 10s by default
+	ReadOnlyGet             bool  `json:"readOnlyGet"` // GET and HEAD requests do not hold lock, changes are saved under short lock
 }
 
-// NewManager Create new Manager with provider name and json config string.
-// provider name:
-// 1. cookie
-// 2. file
-// 3. memory
-// 4. redis
-// 5. mysql
-// json config:
-// 1. is https  default false
-// 2. hashfunc  default sha1
-// 3. hashkey default beegosessionkey
-// 4. maxage default is none
+// UserIDKey session key of logged in user id, see ctrl.Controller.Login
+const UserIDKey = "_uid"
+
+// NewManager Create new Manager with provider selected by cf.ProviderName
+// provider name defaults to cookie, others must be added with Register.
+// cf.ProviderConfig is passed to provider SessionInit,
+// its format depends on provider: json config file for cookie.
 func NewManager(cf *CookieConfig) (*Manager, error) {
-	provider := cookieProvide
+	if cf.ProviderName == "" {
+		cf.ProviderName = "cookie"
+	}
+	provider, err := GetProvider(cf.ProviderName)
+	if err != nil {
+		return nil, err
+	}
 
 	if cf.Maxlifetime == 0 {
 		cf.Maxlifetime = cf.Gclifetime
@@ -64,7 +86,10 @@ func NewManager(cf *CookieConfig) (*Manager, error) {
 		}
 	}
 
-	err := provider.SessionInit(nil, cf.Maxlifetime, cf.ProviderConfig)
+	if rc, ok := provider.(configReceiver); ok {
+		rc.setConfig(cf)
+	}
+	err = provider.SessionInit(nil, cf.Maxlifetime, cf.ProviderConfig)
 	if err != nil {
 		return nil, err
 	}
@@ -77,7 +102,7 @@ func NewManager(cf *CookieConfig) (*Manager, error) {
 }
 
 // GetProvider return current manager's provider
-func (manager *Manager) GetProvider() *CookieProvider {
+func (manager *Manager) GetProvider() Provider {
 	return manager.provider
 }
 
@@ -119,14 +144,36 @@ func (manager *Manager) getSid(r *http.Request) (string, error) {
 
 // SessionStart generate or read the session id from http request.
 // if session id exists, return SessionStore with this id.
-func (manager *Manager) SessionStart(w http.ResponseWriter, r *http.Request) (session *Cookie, err error) {
+func (manager *Manager) SessionStart(w http.ResponseWriter, r *http.Request) (session Store, err error) {
 	sid, errs := manager.getSid(r)
 	if errs != nil {
-		return &Cookie{}, errs
+		return nil, errs
 	}
 
 	if sid != "" {
-		return manager.provider.SessionRead(nil, sid)
+		// unknown server side sid is replaced, client can not choose its id
+		exists, err := manager.provider.SessionExist(nil, sid)
+		if err != nil {
+			return nil, err
+		}
+		if exists {
+			readOnly := manager.readOnly(r)
+			unlock := func() {}
+			if !readOnly {
+				if unlock, err = manager.lock(sid); err != nil {
+					return nil, err
+				}
+			}
+			if session, err = manager.provider.SessionRead(nil, sid); err != nil {
+				unlock()
+				return nil, err
+			}
+			ts := &trackedStore{Store: session, readOnly: readOnly, unlock: unlock}
+			if st, ok := session.(staleStore); ok && st.stale() {
+				ts.dirty = 1
+			}
+			return ts, nil
+		}
 	}
 	// Generate a new session
 	sid, errs = manager.sessionID()
@@ -139,22 +186,12 @@ func (manager *Manager) SessionStart(w http.ResponseWriter, r *http.Request) (se
 	if err != nil {
 		return nil, err
 	}
-	cookie := &http.Cookie{
-		Name:     manager.config.CookieName,
-		Value:    url.QueryEscape(sid),
-		Path:     "/",
-		HttpOnly: !manager.config.DisableHTTPOnly,
-		Secure:   manager.isSecure(r),
-		Domain:   manager.config.Domain,
-	}
-	if manager.config.CookieLifeTime > 0 {
-		cookie.MaxAge = manager.config.CookieLifeTime
-		cookie.Expires = time.Now().Add(time.Duration(manager.config.CookieLifeTime) * time.Second)
-	}
+	session = &trackedStore{Store: session}
+	cookie := manager.config.cookie(url.QueryEscape(sid), manager.isSecure(r))
 	if manager.config.EnableSetCookie {
 		http.SetCookie(w, cookie)
 	}
-	r.AddCookie(cookie)
+	setRequestCookie(r, cookie)
 
 	if manager.config.EnableSidInHTTPHeader {
 		r.Header.Set(manager.config.SessionNameInHTTPHeader, sid)
@@ -172,7 +209,44 @@ func (manager *Manager) GC() {
 }
 
 func (manager *Manager) sessionID() (string, error) {
-	return encodeCookie(cookieProvide.block, cookieProvide.config.SecurityKey, cookieProvide.config.SecurityName, make(map[string]interface{}))
+	if g, ok := manager.provider.(idGenerator); ok {
+		return g.sessionID()
+	}
+	b := generateRandomKey(int(manager.config.SessionIDLength))
+	return manager.config.SessionIDPrefix + hex.EncodeToString(b), nil
+}
+
+// cookie returns session cookie with configured attributes
+// names with __Host- prefix are always Secure with Path "/" and no Domain,
+// names with __Secure- prefix are always Secure
+func (cf *CookieConfig) cookie(value string, secure bool) *http.Cookie {
+	cookie := &http.Cookie{
+		Name:     cf.CookieName,
+		Value:    value,
+		Path:     cf.Path,
+		HttpOnly: !cf.DisableHTTPOnly,
+		Secure:   secure,
+		Domain:   cf.Domain,
+		SameSite: cf.SameSite,
+	}
+	if cookie.Path == "" {
+		cookie.Path = "/"
+	}
+	switch {
+	case strings.HasPrefix(cf.CookieName, "__Host-"):
+		cookie.Secure, cookie.Path, cookie.Domain = true, "/", ""
+	case strings.HasPrefix(cf.CookieName, "__Secure-"):
+		cookie.Secure = true
+	}
+	// browsers drop SameSite=None cookies which are not Secure
+	if cookie.SameSite == http.SameSiteNoneMode {
+		cookie.Secure = true
+	}
+	if cf.CookieLifeTime > 0 {
+		cookie.MaxAge = cf.CookieLifeTime
+		cookie.Expires = time.Now().Add(time.Duration(cf.CookieLifeTime) * time.Second)
+	}
+	return cookie
 }
 
 // Set cookie with https
@@ -190,58 +264,113 @@ func (manager *Manager) isSecure(r *http.Request) bool {
 }
 
 // SessionRegenerateID Regenerate a session id for this SessionStore who's id is saving in http request.
-func (manager *Manager) SessionRegenerateID(w http.ResponseWriter, r *http.Request) (*Cookie, error) {
+// session data is kept under the new id and old id is no longer valid,
+// call it on privilege change such as login to prevent session fixation.
+// st is current session of request, its unsaved changes are saved
+// before regenerating so they move to the new id, it may be nil
+func (manager *Manager) SessionRegenerateID(w http.ResponseWriter, r *http.Request, st Store) (Store, error) {
 	sid, err := manager.sessionID()
 	if err != nil {
-		return &Cookie{}, err
+		return nil, err
+	}
+	if err = manager.SessionSave(w, r, st); err != nil {
+		return nil, err
 	}
 
-	var session *Cookie
+	var session Store
 
-	cookie, err := r.Cookie(manager.config.CookieName)
-	if err != nil || cookie.Value == "" {
-		// delete old cookie
+	oldsid, err := manager.getSid(r)
+	if err != nil {
+		return nil, err
+	}
+	if oldsid == "" {
 		session, err = manager.provider.SessionRead(nil, sid)
-		if err != nil {
-			return nil, err
-		}
-		cookie = &http.Cookie{Name: manager.config.CookieName,
-			Value:    url.QueryEscape(sid),
-			Path:     "/",
-			HttpOnly: !manager.config.DisableHTTPOnly,
-			Secure:   manager.isSecure(r),
-			Domain:   manager.config.Domain,
-		}
 	} else {
-		oldsid, err := url.QueryUnescape(cookie.Value)
-		if err != nil {
-			return nil, err
-		}
-
 		session, err = manager.provider.SessionRegenerate(nil, oldsid, sid)
-		if err != nil {
-			return nil, err
-		}
-
-		cookie.Value = url.QueryEscape(sid)
-		cookie.HttpOnly = true
-		cookie.Path = "/"
 	}
-	if manager.config.CookieLifeTime > 0 {
-		cookie.MaxAge = manager.config.CookieLifeTime
-		cookie.Expires = time.Now().Add(time.Duration(manager.config.CookieLifeTime) * time.Second)
+	if err != nil {
+		return nil, err
 	}
+
+	cookie := manager.config.cookie(url.QueryEscape(sid), manager.isSecure(r))
 	if manager.config.EnableSetCookie {
 		http.SetCookie(w, cookie)
 	}
-	r.AddCookie(cookie)
+	setRequestCookie(r, cookie)
 
 	if manager.config.EnableSidInHTTPHeader {
 		r.Header.Set(manager.config.SessionNameInHTTPHeader, sid)
 		w.Header().Set(manager.config.SessionNameInHTTPHeader, sid)
 	}
 
-	return session, nil
+	// new id must reach the store even if no value changes
+	return &trackedStore{Store: session, dirty: 1}, nil
+}
+
+// setRequestCookie replace cookie of same name in r
+// so later reads in this request see it
+func setRequestCookie(r *http.Request, cookie *http.Cookie) {
+	cookies := r.Cookies()
+	r.Header.Del("Cookie")
+	for _, c := range cookies {
+		if c.Name != cookie.Name {
+			r.AddCookie(c)
+		}
+	}
+	r.AddCookie(cookie)
+}
+
+// SessionSave persist session st if it was changed since it was read,
+// sessions only read by request are not written. Changes of sessions read
+// without lock, see CookieConfig.ReadOnlyGet, are written under lock taken for the save only.
+// It must run before response header is written for cookie sessions
+func (manager *Manager) SessionSave(w http.ResponseWriter, r *http.Request, st Store) error {
+	if st == nil || !Dirty(st) {
+		return nil
+	}
+	if ts, ok := st.(*trackedStore); ok && ts.readOnly {
+		unlock, err := manager.lock(st.SessionID(nil))
+		if err != nil {
+			return err
+		}
+		defer unlock()
+	}
+	return st.Save(nil, nil, r, w)
+}
+
+// SessionRelease release lock of session st taken by SessionStart
+// it is safe to call more than once
+func (manager *Manager) SessionRelease(st Store) {
+	if ts, ok := st.(*trackedStore); ok && ts.unlock != nil {
+		unlock := ts.unlock
+		ts.unlock = nil
+		unlock()
+	}
+}
+
+// readOnly reports whether session of r is read without lock
+func (manager *Manager) readOnly(r *http.Request) bool {
+	return manager.config.ReadOnlyGet && (r.Method == http.MethodGet || r.Method == http.MethodHead)
+}
+
+// lock take provider lock of sid within LockTimeout
+// it does nothing when locking is off or provider is no Locker
+func (manager *Manager) lock(sid string) (func(), error) {
+	l, ok := manager.provider.(Locker)
+	if !manager.config.EnableLock || !ok {
+		return func() {}, nil
+	}
+	timeout := time.Duration(manager.config.LockTimeout) * time.Millisecond
+	if timeout <= 0 {
+		timeout = 10 * time.Second
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	unlock, err := l.SessionLock(ctx, sid)
+	if err == context.DeadlineExceeded {
+		err = ErrLockTimeout
+	}
+	return unlock, err
 }
 
 // SessionDestroy Destroy session by its id in http request cookie.
@@ -259,14 +388,9 @@ func (manager *Manager) SessionDestroy(w http.ResponseWriter, r *http.Request) {
 	sid, _ := url.QueryUnescape(cookie.Value)
 	manager.provider.SessionDestroy(nil, sid)
 	if manager.config.EnableSetCookie {
-		expiration := time.Now()
-		cookie = &http.Cookie{Name: manager.config.CookieName,
-			Path:     "/",
-			HttpOnly: !manager.config.DisableHTTPOnly,
-			Expires:  expiration,
-			MaxAge:   -1,
-			Domain:   manager.config.Domain}
-
+		cookie = manager.config.cookie("", manager.isSecure(r))
+		cookie.Expires = time.Now()
+		cookie.MaxAge = -1
 		http.SetCookie(w, cookie)
 	}
 }
\ No newline at end of file
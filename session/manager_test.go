@@ -0,0 +1,97 @@
+package session
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+
+	"github.com/mellowarex/gon/session/resp"
+)
+
+// managerProviders returns manager config of each registered provider
+func managerProviders(t *testing.T) map[string]*CookieConfig {
+	t.Helper()
+	dir := t.TempDir()
+	cookieConf := filepath.Join(dir, "cookie.json")
+	err := ioutil.WriteFile(cookieConf, []byte(`{"keys": [{"id": "1", "hashKey": "hash", "blockKey": "block"}]}`), 0600)
+	if err != nil {
+		t.Fatal(err)
+	}
+	srv := resp.NewServer()
+	addr, err := srv.Listen("127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { srv.Close() })
+	fakeSQL.lock.Lock()
+	fakeSQL.dbs["manager"] = &fakeDB{rows: make(map[string]fakeRow), lockRow: make(map[string]bool), held: make(map[string]chan struct{})}
+	fakeSQL.lock.Unlock()
+
+	return map[string]*CookieConfig{
+		"memory": {ProviderName: "memory"},
+		"file":   {ProviderName: "file", ProviderConfig: filepath.Join(dir, "file")},
+		"cookie": {ProviderName: "cookie", ProviderConfig: cookieConf},
+		"redis":  {ProviderName: "redis", ProviderConfig: addr},
+		"sql":    {ProviderName: "sql", ProviderConfig: "sessionfake:manager"},
+	}
+}
+
+// nextRequest returns request carrying cookies set by w
+func nextRequest(w *httptest.ResponseRecorder) *http.Request {
+	r := httptest.NewRequest("POST", "/", nil)
+	last := make(map[string]*http.Cookie)
+	for _, c := range w.Result().Cookies() {
+		last[c.Name] = c
+	}
+	for _, c := range last {
+		r.AddCookie(c)
+	}
+	return r
+}
+
+// values set before regenerate in the same request move to the new id
+func TestManagerRegenerateKeepsValues(t *testing.T) {
+	for name, cf := range managerProviders(t) {
+		t.Run(name, func(t *testing.T) {
+			cf.CookieName = "gsid"
+			cf.EnableSetCookie = true
+			cf.Maxlifetime = 3600
+			manager, err := NewManager(cf)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			w := httptest.NewRecorder()
+			r := httptest.NewRequest("POST", "/", nil)
+			st, err := manager.SessionStart(w, r)
+			if err != nil {
+				t.Fatal(err)
+			}
+			oldsid := st.SessionID(nil)
+			st.Set(nil, "user", "alice", r, w)
+			st, err = manager.SessionRegenerateID(w, r, st)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if name != "cookie" && st.SessionID(nil) == oldsid {
+				t.Fatal("session id was not regenerated")
+			}
+			if err := manager.SessionSave(w, r, st); err != nil {
+				t.Fatal(err)
+			}
+			manager.SessionRelease(st)
+
+			r = nextRequest(w)
+			st, err = manager.SessionStart(httptest.NewRecorder(), r)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer manager.SessionRelease(st)
+			if v := st.Get(nil, "user"); v != "alice" {
+				t.Fatalf("user = %v, want alice", v)
+			}
+		})
+	}
+}
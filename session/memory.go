@@ -0,0 +1,226 @@
+package session
+
+import (
+	"container/list"
+	"context"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+var memProvider = &MemProvider{list: list.New(), sessions: make(map[string]*list.Element), locks: make(map[string]*memLock)}
+
+// MemStore memory session store
+// it saved sessions in a map in memory
+type MemStore struct {
+	sid          string
+	timeAccessed time.Time
+	values       map[string]interface{}
+	lock         sync.RWMutex
+}
+
+// Set value to memory session
+func (st *MemStore) Set(ctx context.Context, key string, value interface{}, r *http.Request, w http.ResponseWriter) error {
+	st.lock.Lock()
+	defer st.lock.Unlock()
+	st.values[key] = value
+	return nil
+}
+
+// Get value from memory session by key
+func (st *MemStore) Get(ctx context.Context, key string) interface{} {
+	st.lock.RLock()
+	defer st.lock.RUnlock()
+	if v, ok := st.values[key]; ok {
+		return v
+	}
+	return nil
+}
+
+// Delete in memory session by key
+func (st *MemStore) Delete(ctx context.Context, key string, r *http.Request, w http.ResponseWriter) error {
+	st.lock.Lock()
+	defer st.lock.Unlock()
+	delete(st.values, key)
+	return nil
+}
+
+// Flush clear all values in memory session
+func (st *MemStore) Flush(ctx context.Context, r *http.Request, w http.ResponseWriter) error {
+	st.lock.Lock()
+	defer st.lock.Unlock()
+	st.values = make(map[string]interface{})
+	return nil
+}
+
+// Save Implement method, values already live in memory
+func (st *MemStore) Save(ctx context.Context, rr interface{}, r *http.Request, w http.ResponseWriter) error {
+	return nil
+}
+
+// SessionID get this id of memory session store
+func (st *MemStore) SessionID(context.Context) string {
+	return st.sid
+}
+
+// MemProvider Implement the provider interface
+// sessions are kept in least recently used order,
+// when maxSessions is reached the least recently used one is evicted
+type MemProvider struct {
+	lock        sync.RWMutex             // locker
+	sessions    map[string]*list.Element // map in memory
+	list        *list.List               // for gc and eviction, front is most recently used
+	maxlifetime int64
+	maxSessions int
+	locksLock   sync.Mutex
+	locks       map[string]*memLock // request locks by sid
+}
+
+// memLock is a mutex which can be waited on with timeout
+// refs counts holders and waiters so unused locks are dropped
+type memLock struct {
+	ch   chan struct{}
+	refs int
+}
+
+// SessionInit init memory session
+// config is the max count of sessions kept, empty or 0 means no limit
+func (pder *MemProvider) SessionInit(ctx context.Context, maxlifetime int64, config string) error {
+	pder.lock.Lock()
+	defer pder.lock.Unlock()
+	pder.maxlifetime = maxlifetime
+	pder.maxSessions = 0
+	if config != "" {
+		n, err := strconv.Atoi(config)
+		if err != nil {
+			return err
+		}
+		pder.maxSessions = n
+	}
+	return nil
+}
+
+// SessionRead get memory session store by sid
+// unknown sid creates new empty session
+func (pder *MemProvider) SessionRead(ctx context.Context, sid string) (Store, error) {
+	pder.lock.Lock()
+	defer pder.lock.Unlock()
+	if element, ok := pder.sessions[sid]; ok {
+		st := element.Value.(*MemStore)
+		st.timeAccessed = time.Now()
+		pder.list.MoveToFront(element)
+		return st, nil
+	}
+	return pder.add(sid, make(map[string]interface{})), nil
+}
+
+// add inserts new session, evicting least recently used ones over capacity
+// caller must hold pder.lock
+func (pder *MemProvider) add(sid string, values map[string]interface{}) *MemStore {
+	st := &MemStore{sid: sid, timeAccessed: time.Now(), values: values}
+	pder.sessions[sid] = pder.list.PushFront(st)
+	for pder.maxSessions > 0 && pder.list.Len() > pder.maxSessions {
+		pder.remove(pder.list.Back())
+	}
+	return st
+}
+
+// remove deletes session of element
+// caller must hold pder.lock
+func (pder *MemProvider) remove(element *list.Element) {
+	pder.list.Remove(element)
+	delete(pder.sessions, element.Value.(*MemStore).sid)
+}
+
+// SessionExist check session store exist in memory session by sid
+func (pder *MemProvider) SessionExist(ctx context.Context, sid string) (bool, error) {
+	pder.lock.RLock()
+	defer pder.lock.RUnlock()
+	_, ok := pder.sessions[sid]
+	return ok, nil
+}
+
+// SessionRegenerate moves data of oldsid to sid
+// old id is removed in the same step, so no request sees both
+func (pder *MemProvider) SessionRegenerate(ctx context.Context, oldsid, sid string) (Store, error) {
+	pder.lock.Lock()
+	defer pder.lock.Unlock()
+	if element, ok := pder.sessions[oldsid]; ok {
+		st := element.Value.(*MemStore)
+		delete(pder.sessions, oldsid)
+		st.lock.Lock()
+		st.sid = sid
+		st.lock.Unlock()
+		st.timeAccessed = time.Now()
+		pder.sessions[sid] = element
+		pder.list.MoveToFront(element)
+		return st, nil
+	}
+	return pder.add(sid, make(map[string]interface{})), nil
+}
+
+// SessionDestroy delete session store in memory session by id
+func (pder *MemProvider) SessionDestroy(ctx context.Context, sid string) error {
+	pder.lock.Lock()
+	defer pder.lock.Unlock()
+	if element, ok := pder.sessions[sid]; ok {
+		pder.remove(element)
+	}
+	return nil
+}
+
+// SessionGC clean expired session stores in memory session
+// sessions not accessed within maxlifetime are removed
+func (pder *MemProvider) SessionGC(context.Context) {
+	pder.lock.Lock()
+	defer pder.lock.Unlock()
+	deadline := time.Now().Add(-time.Duration(pder.maxlifetime) * time.Second)
+	for element := pder.list.Back(); element != nil; element = pder.list.Back() {
+		if !element.Value.(*MemStore).timeAccessed.Before(deadline) {
+			break
+		}
+		pder.remove(element)
+	}
+}
+
+// SessionAll get count number of memory session
+func (pder *MemProvider) SessionAll(context.Context) int {
+	pder.lock.RLock()
+	defer pder.lock.RUnlock()
+	return pder.list.Len()
+}
+
+// SessionLock lock memory session sid until returned func is called
+func (pder *MemProvider) SessionLock(ctx context.Context, sid string) (func(), error) {
+	pder.locksLock.Lock()
+	l, ok := pder.locks[sid]
+	if !ok {
+		l = &memLock{ch: make(chan struct{}, 1)}
+		pder.locks[sid] = l
+	}
+	l.refs++
+	pder.locksLock.Unlock()
+
+	release := func() {
+		pder.locksLock.Lock()
+		if l.refs--; l.refs == 0 {
+			delete(pder.locks, sid)
+		}
+		pder.locksLock.Unlock()
+	}
+	select {
+	case l.ch <- struct{}{}:
+		return func() {
+			<-l.ch
+			release()
+		}, nil
+	case <-ctx.Done():
+		release()
+		return nil, ctx.Err()
+	}
+}
+
+func init() {
+	Register("memory", memProvider)
+}
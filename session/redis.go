@@ -0,0 +1,235 @@
+package session
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/mellowarex/gon/session/resp"
+)
+
+var redisProvider = &RedisProvider{}
+
+// DefaultRedisKeyPrefix is used for redis keys
+// when SessionIDPrefix is not configured
+var DefaultRedisKeyPrefix = "gonsession:"
+
+// RedisStore redis session store
+type RedisStore struct {
+	sid      string
+	provider *RedisProvider
+	lock     sync.RWMutex
+	values   map[string]interface{}
+}
+
+// Set value in redis session
+func (rs *RedisStore) Set(ctx context.Context, key string, value interface{}, r *http.Request, w http.ResponseWriter) error {
+	rs.lock.Lock()
+	defer rs.lock.Unlock()
+	rs.values[key] = value
+	return nil
+}
+
+// Get value in redis session
+func (rs *RedisStore) Get(ctx context.Context, key string) interface{} {
+	rs.lock.RLock()
+	defer rs.lock.RUnlock()
+	if v, ok := rs.values[key]; ok {
+		return v
+	}
+	return nil
+}
+
+// Delete value in redis session
+func (rs *RedisStore) Delete(ctx context.Context, key string, r *http.Request, w http.ResponseWriter) error {
+	rs.lock.Lock()
+	defer rs.lock.Unlock()
+	delete(rs.values, key)
+	return nil
+}
+
+// Flush clear all values in redis session
+func (rs *RedisStore) Flush(ctx context.Context, r *http.Request, w http.ResponseWriter) error {
+	rs.lock.Lock()
+	defer rs.lock.Unlock()
+	rs.values = make(map[string]interface{})
+	return nil
+}
+
+// SessionID get redis session id
+func (rs *RedisStore) SessionID(context.Context) string {
+	return rs.sid
+}
+
+// Save save redis session values and reset its ttl
+func (rs *RedisStore) Save(ctx context.Context, rr interface{}, r *http.Request, w http.ResponseWriter) error {
+	rs.lock.RLock()
+	b, err := EncodeGob(rs.values)
+	rs.lock.RUnlock()
+	if err != nil {
+		return err
+	}
+	return rs.provider.set(rs.sid, b)
+}
+
+// RedisProvider redis session provider
+// it speaks RESP so any compatible server works,
+// resp.Server can stand in for one in development and tests.
+// Keys are sids, which start with SessionIDPrefix, or DefaultRedisKeyPrefix + sid
+// when it is not configured, and expire after Maxlifetime seconds
+type RedisProvider struct {
+	maxlifetime int64
+	idPrefix    string // SessionIDPrefix, sids carry it already
+	prefix      string // prefix of all session keys
+	pool        *resp.Pool
+}
+
+// setConfig take key prefix from manager config
+func (rp *RedisProvider) setConfig(cf *CookieConfig) {
+	rp.idPrefix = cf.SessionIDPrefix
+}
+
+// SessionInit init redis session
+// config is "addr[,poolsize[,password[,dbnum[,retries]]]]"
+// e.g. "127.0.0.1:6379,100,secret,0,2"
+func (rp *RedisProvider) SessionInit(ctx context.Context, maxlifetime int64, config string) error {
+	rp.maxlifetime = maxlifetime
+	rp.prefix = rp.idPrefix
+	if rp.prefix == "" {
+		rp.prefix = DefaultRedisKeyPrefix
+	}
+	conf := strings.Split(config, ",")
+	addr := strings.TrimSpace(conf[0])
+	if addr == "" {
+		addr = "127.0.0.1:6379"
+	}
+	pool := resp.NewPool(addr, 100)
+	var err error
+	if len(conf) > 1 {
+		if pool.MaxIdle, err = strconv.Atoi(strings.TrimSpace(conf[1])); err != nil || pool.MaxIdle <= 0 {
+			pool.MaxIdle = 100
+		}
+	}
+	if len(conf) > 2 {
+		pool.Password = conf[2]
+	}
+	if len(conf) > 3 {
+		if pool.DB, err = strconv.Atoi(strings.TrimSpace(conf[3])); err != nil {
+			pool.DB = 0
+		}
+	}
+	if len(conf) > 4 {
+		if pool.MaxRetries, err = strconv.Atoi(strings.TrimSpace(conf[4])); err != nil || pool.MaxRetries < 0 {
+			pool.MaxRetries = 2
+		}
+	}
+	if rp.pool != nil {
+		rp.pool.Close()
+	}
+	rp.pool = pool
+	_, err = pool.Do("PING")
+	return err
+}
+
+// key returns redis key of sid
+// sids made by manager start with SessionIDPrefix, so it is not added again,
+// any other sid is kept under prefix too
+func (rp *RedisProvider) key(sid string) string {
+	if strings.HasPrefix(sid, rp.prefix) {
+		return sid
+	}
+	return rp.prefix + sid
+}
+
+// set store data of sid with maxlifetime ttl
+func (rp *RedisProvider) set(sid string, data []byte) error {
+	if rp.maxlifetime > 0 {
+		_, err := rp.pool.Do("SET", rp.key(sid), data, "EX", rp.maxlifetime)
+		return err
+	}
+	_, err := rp.pool.Do("SET", rp.key(sid), data)
+	return err
+}
+
+// SessionRead read redis session by sid
+// missing session is empty and not stored until it is saved
+func (rp *RedisProvider) SessionRead(ctx context.Context, sid string) (Store, error) {
+	b, err := resp.Bytes(rp.pool.Do("GET", rp.key(sid)))
+	if err != nil && err != resp.ErrNil {
+		return nil, err
+	}
+	var values map[string]interface{}
+	if len(b) > 0 {
+		if values, err = DecodeGob(b); err != nil {
+			return nil, err
+		}
+	}
+	if values == nil {
+		values = make(map[string]interface{})
+	}
+	return &RedisStore{sid: sid, provider: rp, values: values}, nil
+}
+
+// SessionExist check redis session exist by sid
+func (rp *RedisProvider) SessionExist(ctx context.Context, sid string) (bool, error) {
+	n, err := resp.Int64(rp.pool.Do("EXISTS", rp.key(sid)))
+	return n == 1, err
+}
+
+// SessionRegenerate move redis session of oldsid to sid
+func (rp *RedisProvider) SessionRegenerate(ctx context.Context, oldsid, sid string) (Store, error) {
+	ok, err := rp.SessionExist(ctx, oldsid)
+	if err != nil {
+		return nil, err
+	}
+	if ok {
+		moved, err := resp.Int64(rp.pool.Do("RENAMENX", rp.key(oldsid), rp.key(sid)))
+		if err != nil {
+			return nil, err
+		}
+		if moved == 0 {
+			return nil, errors.New("session: newsid " + sid + " exist")
+		}
+		if rp.maxlifetime > 0 {
+			if _, err := rp.pool.Do("EXPIRE", rp.key(sid), rp.maxlifetime); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return rp.SessionRead(ctx, sid)
+}
+
+// SessionDestroy delete redis session by sid
+func (rp *RedisProvider) SessionDestroy(ctx context.Context, sid string) error {
+	_, err := rp.pool.Do("DEL", rp.key(sid))
+	return err
+}
+
+// SessionGC Implement method, redis expires keys by ttl
+func (rp *RedisProvider) SessionGC(context.Context) {
+}
+
+// SessionAll count redis sessions under key prefix
+func (rp *RedisProvider) SessionAll(context.Context) int {
+	total := 0
+	cursor := "0"
+	for {
+		values, err := resp.Values(rp.pool.Do("SCAN", cursor, "MATCH", rp.prefix+"*", "COUNT", 1000))
+		if err != nil || len(values) != 2 {
+			return total
+		}
+		keys, _ := values[1].([]interface{})
+		total += len(keys)
+		next, _ := values[0].([]byte)
+		if cursor = string(next); cursor == "0" || cursor == "" {
+			return total
+		}
+	}
+}
+
+func init() {
+	Register("redis", redisProvider)
+}
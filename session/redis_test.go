@@ -0,0 +1,264 @@
+package session
+
+import (
+	"context"
+	"testing"
+
+	"github.com/mellowarex/gon/session/resp"
+)
+
+// newRedisTest returns provider connected to in-process server
+func newRedisTest(t *testing.T, cf *CookieConfig, maxlifetime int64) (*RedisProvider, *resp.Server, string) {
+	t.Helper()
+	srv := resp.NewServer()
+	addr, err := srv.Listen("127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { srv.Close() })
+	rp := &RedisProvider{}
+	rp.setConfig(cf)
+	if err := rp.SessionInit(context.Background(), maxlifetime, addr); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { rp.pool.Close() })
+	return rp, srv, addr
+}
+
+func redisKeys(t *testing.T, pool *resp.Pool) map[string]bool {
+	t.Helper()
+	values, err := resp.Values(pool.Do("KEYS", "*"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	keys := make(map[string]bool)
+	for _, v := range values {
+		keys[string(v.([]byte))] = true
+	}
+	return keys
+}
+
+func TestRedisProviderReadWrite(t *testing.T) {
+	ctx := context.Background()
+	rp, _, _ := newRedisTest(t, &CookieConfig{}, 3600)
+
+	st, err := rp.SessionRead(ctx, "abc")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v := st.Get(ctx, "user"); v != nil {
+		t.Fatalf("new session has user %v", v)
+	}
+	// unknown sid is not stored until saved
+	if ok, err := rp.SessionExist(ctx, "abc"); ok || err != nil {
+		t.Fatalf("SessionExist after read = %v, %v", ok, err)
+	}
+	st.Set(ctx, "user", "alice", nil, nil)
+	if err := st.Save(ctx, nil, nil, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	if ok, err := rp.SessionExist(ctx, "abc"); !ok || err != nil {
+		t.Fatalf("SessionExist = %v, %v", ok, err)
+	}
+	st, err = rp.SessionRead(ctx, "abc")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v := st.Get(ctx, "user"); v != "alice" {
+		t.Fatalf("user = %v, want alice", v)
+	}
+	if n := rp.SessionAll(ctx); n != 1 {
+		t.Fatalf("SessionAll = %d, want 1", n)
+	}
+}
+
+func TestRedisProviderKeyPrefix(t *testing.T) {
+	ctx := context.Background()
+	tests := []struct {
+		prefix string
+		sid    string
+		key    string
+	}{
+		{"", "abc", DefaultRedisKeyPrefix + "abc"},
+		{"app:", "app:abc", "app:abc"},
+		// sids not made by manager stay under prefix
+		{"app:", "other:abc", "app:other:abc"},
+	}
+	for _, tt := range tests {
+		rp, _, _ := newRedisTest(t, &CookieConfig{SessionIDPrefix: tt.prefix}, 3600)
+		st, err := rp.SessionRead(ctx, tt.sid)
+		if err != nil {
+			t.Fatal(err)
+		}
+		st.Set(ctx, "k", "v", nil, nil)
+		if err := st.Save(ctx, nil, nil, nil); err != nil {
+			t.Fatal(err)
+		}
+		if keys := redisKeys(t, rp.pool); len(keys) != 1 || !keys[tt.key] {
+			t.Errorf("prefix %q sid %q: keys = %v, want %s", tt.prefix, tt.sid, keys, tt.key)
+		}
+	}
+}
+
+func TestRedisProviderRegenerate(t *testing.T) {
+	ctx := context.Background()
+	rp, _, _ := newRedisTest(t, &CookieConfig{}, 3600)
+
+	st, _ := rp.SessionRead(ctx, "old")
+	st.Set(ctx, "user", "alice", nil, nil)
+	st.Save(ctx, nil, nil, nil)
+
+	st, err := rp.SessionRegenerate(ctx, "old", "new")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if st.SessionID(ctx) != "new" || st.Get(ctx, "user") != "alice" {
+		t.Fatalf("regenerated session %s has user %v", st.SessionID(ctx), st.Get(ctx, "user"))
+	}
+	if ok, _ := rp.SessionExist(ctx, "old"); ok {
+		t.Fatal("old session still exists")
+	}
+
+	st, _ = rp.SessionRead(ctx, "other")
+	st.Save(ctx, nil, nil, nil)
+	if _, err := rp.SessionRegenerate(ctx, "new", "other"); err == nil {
+		t.Fatal("regenerate to existing sid succeeded")
+	}
+	if st, _ := rp.SessionRead(ctx, "new"); st.Get(ctx, "user") != "alice" {
+		t.Fatal("failed regenerate lost session")
+	}
+}
+
+func TestRedisProviderDestroy(t *testing.T) {
+	ctx := context.Background()
+	rp, _, _ := newRedisTest(t, &CookieConfig{}, 3600)
+
+	rp.SessionRead(ctx, "abc")
+	if err := rp.SessionDestroy(ctx, "abc"); err != nil {
+		t.Fatal(err)
+	}
+	if ok, _ := rp.SessionExist(ctx, "abc"); ok {
+		t.Fatal("destroyed session exists")
+	}
+	if n := rp.SessionAll(ctx); n != 0 {
+		t.Fatalf("SessionAll = %d, want 0", n)
+	}
+}
+
+// sessions expire by ttl, SessionGC has nothing to do
+func TestRedisProviderGC(t *testing.T) {
+	ctx := context.Background()
+	rp, _, _ := newRedisTest(t, &CookieConfig{}, 60)
+
+	st, _ := rp.SessionRead(ctx, "abc")
+	st.Save(ctx, nil, nil, nil)
+	rp.SessionGC(ctx)
+	ttl, err := resp.Int64(rp.pool.Do("TTL", rp.key("abc")))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ttl <= 0 || ttl > 60 {
+		t.Fatalf("TTL = %d, want 1..60", ttl)
+	}
+	if ok, _ := rp.SessionExist(ctx, "abc"); !ok {
+		t.Fatal("SessionGC removed live session")
+	}
+}
+
+func TestRedisProviderAuthSelect(t *testing.T) {
+	ctx := context.Background()
+	srv := resp.NewServer()
+	srv.Password = "secret"
+	addr, err := srv.Listen("127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer srv.Close()
+
+	rp := &RedisProvider{}
+	rp.setConfig(&CookieConfig{})
+	if err := rp.SessionInit(ctx, 3600, addr+",10,wrong"); err == nil {
+		t.Fatal("SessionInit with wrong password succeeded")
+	}
+	if err := rp.SessionInit(ctx, 3600, addr+",10,secret,3"); err != nil {
+		t.Fatal(err)
+	}
+	defer rp.pool.Close()
+	st, _ := rp.SessionRead(ctx, "abc")
+	st.Save(ctx, nil, nil, nil)
+
+	for db, want := range map[int]bool{0: false, 3: true} {
+		pool := resp.NewPool(addr, 1)
+		pool.Password = "secret"
+		pool.DB = db
+		keys := redisKeys(t, pool)
+		pool.Close()
+		if keys[rp.key("abc")] != want {
+			t.Errorf("db %d has session = %v, want %v", db, !want, want)
+		}
+	}
+}
+
+func TestRedisProviderReconnect(t *testing.T) {
+	ctx := context.Background()
+	rp, srv, addr := newRedisTest(t, &CookieConfig{}, 3600)
+
+	st, _ := rp.SessionRead(ctx, "abc")
+	st.Set(ctx, "user", "alice", nil, nil)
+	st.Save(ctx, nil, nil, nil)
+
+	// restart server, pooled connections are broken
+	srv.Close()
+	srv = resp.NewServer()
+	if _, err := srv.Listen(addr); err != nil {
+		t.Fatal(err)
+	}
+	defer srv.Close()
+
+	st, err := rp.SessionRead(ctx, "abc")
+	if err != nil {
+		t.Fatalf("read after restart: %v", err)
+	}
+	st.Set(ctx, "user", "bob", nil, nil)
+	if err := st.Save(ctx, nil, nil, nil); err != nil {
+		t.Fatalf("save after restart: %v", err)
+	}
+	if st, _ := rp.SessionRead(ctx, "abc"); st.Get(ctx, "user") != "bob" {
+		t.Fatalf("user = %v, want bob", st.Get(ctx, "user"))
+	}
+}
+
+// commands which may have reached the server are not sent again
+func TestPoolDoRetry(t *testing.T) {
+	srv := resp.NewServer()
+	addr, err := srv.Listen("127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	pool := resp.NewPool(addr, 10)
+	defer pool.Close()
+	// leave one connection in pool
+	if _, err := pool.Do("PING"); err != nil {
+		t.Fatal(err)
+	}
+
+	srv.Close()
+	srv = resp.NewServer()
+	if _, err := srv.Listen(addr); err != nil {
+		t.Fatal(err)
+	}
+	defer srv.Close()
+	other := resp.NewPool(addr, 1)
+	defer other.Close()
+	if _, err := other.Do("SET", "a", "v"); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := pool.Do("RENAMENX", "a", "b"); err == nil {
+		t.Fatal("RENAMENX on broken connection was retried")
+	}
+	if moved, err := resp.Int64(pool.Do("RENAMENX", "a", "b")); err != nil || moved != 1 {
+		t.Fatalf("RENAMENX = %d, %v", moved, err)
+	}
+}
@@ -0,0 +1,331 @@
+// Package resp is a minimal client for the Redis serialization protocol (RESP)
+// it covers what session providers need: pooled connections,
+// AUTH and SELECT on dial and retry of idempotent commands on broken connections.
+//
+// Usage:
+//
+//	pool := resp.NewPool("127.0.0.1:6379", 10)
+//	defer pool.Close()
+//	_, err := pool.Do("SET", "key", "value", "EX", 60)
+//	b, err := resp.Bytes(pool.Do("GET", "key"))
+package resp
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+var (
+	// ErrNil is returned by reply helpers for nil bulk replies
+	ErrNil = errors.New("resp: nil reply")
+	// ErrPoolClosed is returned when Do is called on closed pool
+	ErrPoolClosed = errors.New("resp: pool closed")
+	// ErrProtocol is returned on malformed server reply
+	ErrProtocol = errors.New("resp: protocol error")
+)
+
+// Error is an error reply sent by server
+// it is not retried since the command reached the server
+type Error string
+
+func (e Error) Error() string { return string(e) }
+
+// Conn single connection to RESP server
+type Conn struct {
+	conn    net.Conn
+	br      *bufio.Reader
+	bw      *bufio.Writer
+	timeout time.Duration
+	broken  bool
+}
+
+// Dial connect to addr
+// timeout applies to dial and to every command, 0 means no timeout
+func Dial(addr string, timeout time.Duration) (*Conn, error) {
+	c, err := net.DialTimeout("tcp", addr, timeout)
+	if err != nil {
+		return nil, err
+	}
+	return &Conn{conn: c, br: bufio.NewReader(c), bw: bufio.NewWriter(c), timeout: timeout}, nil
+}
+
+// Close close connection
+func (c *Conn) Close() error {
+	return c.conn.Close()
+}
+
+// Do send command with args and read its reply
+// reply is one of string, int64, []byte, []interface{} or nil,
+// server error replies are returned as Error
+func (c *Conn) Do(cmd string, args ...interface{}) (interface{}, error) {
+	if c.timeout > 0 {
+		c.conn.SetDeadline(time.Now().Add(c.timeout))
+	}
+	if err := c.writeCommand(cmd, args); err != nil {
+		c.broken = true
+		return nil, err
+	}
+	reply, err := ReadReply(c.br)
+	if err != nil {
+		if _, ok := err.(Error); !ok {
+			c.broken = true
+		}
+		return nil, err
+	}
+	return reply, nil
+}
+
+func (c *Conn) writeCommand(cmd string, args []interface{}) error {
+	fmt.Fprintf(c.bw, "*%d\r\n", len(args)+1)
+	writeBulk(c.bw, []byte(cmd))
+	for _, arg := range args {
+		switch v := arg.(type) {
+		case []byte:
+			writeBulk(c.bw, v)
+		case string:
+			writeBulk(c.bw, []byte(v))
+		case int:
+			writeBulk(c.bw, []byte(strconv.Itoa(v)))
+		case int64:
+			writeBulk(c.bw, []byte(strconv.FormatInt(v, 10)))
+		case nil:
+			writeBulk(c.bw, nil)
+		default:
+			writeBulk(c.bw, []byte(fmt.Sprint(v)))
+		}
+	}
+	return c.bw.Flush()
+}
+
+func writeBulk(w *bufio.Writer, b []byte) {
+	w.WriteString("$")
+	w.WriteString(strconv.Itoa(len(b)))
+	w.WriteString("\r\n")
+	w.Write(b)
+	w.WriteString("\r\n")
+}
+
+// ReadReply read one RESP value from r
+func ReadReply(r *bufio.Reader) (interface{}, error) {
+	line, err := readLine(r)
+	if err != nil {
+		return nil, err
+	}
+	switch line[0] {
+	case '+':
+		return string(line[1:]), nil
+	case '-':
+		return nil, Error(line[1:])
+	case ':':
+		return strconv.ParseInt(string(line[1:]), 10, 64)
+	case '$':
+		n, err := strconv.Atoi(string(line[1:]))
+		if err != nil || n < -1 {
+			return nil, ErrProtocol
+		}
+		if n == -1 {
+			return nil, nil
+		}
+		b := make([]byte, n+2)
+		if _, err := io.ReadFull(r, b); err != nil {
+			return nil, err
+		}
+		return b[:n], nil
+	case '*':
+		n, err := strconv.Atoi(string(line[1:]))
+		if err != nil || n < -1 {
+			return nil, ErrProtocol
+		}
+		if n == -1 {
+			return nil, nil
+		}
+		values := make([]interface{}, n)
+		for i := range values {
+			if values[i], err = ReadReply(r); err != nil {
+				return nil, err
+			}
+		}
+		return values, nil
+	}
+	return nil, ErrProtocol
+}
+
+func readLine(r *bufio.Reader) ([]byte, error) {
+	line, err := r.ReadSlice('\n')
+	if err != nil {
+		return nil, err
+	}
+	if len(line) < 3 || line[len(line)-2] != '\r' {
+		return nil, ErrProtocol
+	}
+	return line[:len(line)-2], nil
+}
+
+// Pool pool of connections to one server
+type Pool struct {
+	Addr       string
+	Password   string
+	DB         int
+	MaxIdle    int           // idle connections kept for reuse
+	MaxRetries int           // retries of command on network errors, see Do
+	Timeout    time.Duration // dial and command timeout
+
+	lock   sync.Mutex
+	idle   []*Conn
+	closed bool
+}
+
+// NewPool returns pool for addr keeping at most maxIdle idle connections
+func NewPool(addr string, maxIdle int) *Pool {
+	return &Pool{Addr: addr, MaxIdle: maxIdle, MaxRetries: 2, Timeout: 5 * time.Second}
+}
+
+// Get get idle connection or dial new one
+func (p *Pool) Get() (*Conn, error) {
+	p.lock.Lock()
+	if p.closed {
+		p.lock.Unlock()
+		return nil, ErrPoolClosed
+	}
+	if n := len(p.idle); n > 0 {
+		c := p.idle[n-1]
+		p.idle = p.idle[:n-1]
+		p.lock.Unlock()
+		return c, nil
+	}
+	p.lock.Unlock()
+
+	c, err := Dial(p.Addr, p.Timeout)
+	if err != nil {
+		return nil, err
+	}
+	if p.Password != "" {
+		if _, err := c.Do("AUTH", p.Password); err != nil {
+			c.Close()
+			return nil, err
+		}
+	}
+	if p.DB != 0 {
+		if _, err := c.Do("SELECT", p.DB); err != nil {
+			c.Close()
+			return nil, err
+		}
+	}
+	return c, nil
+}
+
+// Put return connection to pool
+// broken connections and those above MaxIdle are closed
+func (p *Pool) Put(c *Conn) {
+	p.lock.Lock()
+	if c.broken || p.closed || len(p.idle) >= p.MaxIdle {
+		p.lock.Unlock()
+		c.Close()
+		return
+	}
+	p.idle = append(p.idle, c)
+	p.lock.Unlock()
+}
+
+// idempotent commands may run twice without changing their result,
+// so they are retried after a connection failed with the command sent
+var idempotent = map[string]bool{
+	"PING": true, "GET": true, "SET": true, "SETEX": true, "DEL": true,
+	"EXISTS": true, "EXPIRE": true, "TTL": true, "KEYS": true, "SCAN": true,
+	"DBSIZE": true,
+}
+
+// Do run command on pooled connection
+// failures to get a connection are retried up to MaxRetries times,
+// failures of sent command only for idempotent ones: e.g. RENAMENX
+// which reached the server would fail when retried.
+// Error replies are not retried
+func (p *Pool) Do(cmd string, args ...interface{}) (reply interface{}, err error) {
+	retry := idempotent[strings.ToUpper(cmd)]
+	for attempt := 0; attempt <= p.MaxRetries; attempt++ {
+		if attempt > 0 {
+			time.Sleep(time.Duration(attempt) * 50 * time.Millisecond)
+		}
+		var c *Conn
+		if c, err = p.Get(); err != nil {
+			if err == ErrPoolClosed {
+				return nil, err
+			}
+			continue
+		}
+		reply, err = c.Do(cmd, args...)
+		p.Put(c)
+		if err == nil {
+			return reply, nil
+		}
+		if _, ok := err.(Error); ok || !retry {
+			return nil, err
+		}
+	}
+	return nil, err
+}
+
+// Close close pool and its idle connections
+func (p *Pool) Close() error {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	p.closed = true
+	for _, c := range p.idle {
+		c.Close()
+	}
+	p.idle = nil
+	return nil
+}
+
+// Bytes convert bulk reply to []byte
+func Bytes(reply interface{}, err error) ([]byte, error) {
+	if err != nil {
+		return nil, err
+	}
+	switch v := reply.(type) {
+	case []byte:
+		return v, nil
+	case string:
+		return []byte(v), nil
+	case nil:
+		return nil, ErrNil
+	}
+	return nil, fmt.Errorf("resp: unexpected reply type %T", reply)
+}
+
+// Int64 convert integer reply to int64
+func Int64(reply interface{}, err error) (int64, error) {
+	if err != nil {
+		return 0, err
+	}
+	switch v := reply.(type) {
+	case int64:
+		return v, nil
+	case []byte:
+		return strconv.ParseInt(string(v), 10, 64)
+	case nil:
+		return 0, ErrNil
+	}
+	return 0, fmt.Errorf("resp: unexpected reply type %T", reply)
+}
+
+// Values convert array reply to []interface{}
+func Values(reply interface{}, err error) ([]interface{}, error) {
+	if err != nil {
+		return nil, err
+	}
+	switch v := reply.(type) {
+	case []interface{}:
+		return v, nil
+	case nil:
+		return nil, ErrNil
+	}
+	return nil, fmt.Errorf("resp: unexpected reply type %T", reply)
+}
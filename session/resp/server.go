@@ -0,0 +1,345 @@
+package resp
+
+import (
+	"bufio"
+	"fmt"
+	"net"
+	"path"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Server in-process RESP server keeping keys of 16 databases in memory
+// it implements the commands used by the redis session provider
+// so sessions work in development and tests without a real server:
+// PING, AUTH, SELECT, GET, SET [EX|PX], SETEX, DEL, EXISTS, EXPIRE,
+// TTL, RENAME, RENAMENX, KEYS, SCAN, DBSIZE, FLUSHDB and QUIT
+//
+//	srv := resp.NewServer()
+//	addr, err := srv.Listen("127.0.0.1:0")
+//	defer srv.Close()
+type Server struct {
+	Password string
+
+	lock     sync.Mutex
+	dbs      map[int]map[string]entry
+	listener net.Listener
+	conns    map[net.Conn]struct{}
+}
+
+type entry struct {
+	value   []byte
+	expires time.Time
+}
+
+func (e entry) expired(now time.Time) bool {
+	return !e.expires.IsZero() && !now.Before(e.expires)
+}
+
+// NewServer returns empty server
+func NewServer() *Server {
+	return &Server{dbs: make(map[int]map[string]entry), conns: make(map[net.Conn]struct{})}
+}
+
+// Listen start serving on addr in background and return listening address
+// use port 0 to pick a free port
+func (s *Server) Listen(addr string) (string, error) {
+	l, err := net.Listen("tcp", addr)
+	if err != nil {
+		return "", err
+	}
+	s.lock.Lock()
+	s.listener = l
+	s.lock.Unlock()
+	go s.Serve(l)
+	return l.Addr().String(), nil
+}
+
+// Serve accept connections on l until it is closed
+func (s *Server) Serve(l net.Listener) error {
+	for {
+		c, err := l.Accept()
+		if err != nil {
+			return err
+		}
+		s.lock.Lock()
+		s.conns[c] = struct{}{}
+		s.lock.Unlock()
+		go s.serveConn(c)
+	}
+}
+
+// Close stop listening and close client connections
+func (s *Server) Close() error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	for c := range s.conns {
+		c.Close()
+	}
+	if s.listener != nil {
+		return s.listener.Close()
+	}
+	return nil
+}
+
+func (s *Server) serveConn(c net.Conn) {
+	defer func() {
+		s.lock.Lock()
+		delete(s.conns, c)
+		s.lock.Unlock()
+		c.Close()
+	}()
+	br := bufio.NewReader(c)
+	bw := bufio.NewWriter(c)
+	authed := s.Password == ""
+	db := 0
+	for {
+		req, err := ReadReply(br)
+		if err != nil {
+			return
+		}
+		args, ok := req.([]interface{})
+		if !ok || len(args) == 0 {
+			writeValue(bw, Error("ERR protocol error"))
+			bw.Flush()
+			return
+		}
+		argv := make([]string, len(args))
+		for i, a := range args {
+			b, _ := a.([]byte)
+			argv[i] = string(b)
+		}
+		cmd := strings.ToUpper(argv[0])
+		var reply interface{}
+		switch {
+		case cmd == "QUIT":
+			writeValue(bw, "OK")
+			bw.Flush()
+			return
+		case cmd == "AUTH":
+			if len(argv) == 2 && argv[1] == s.Password {
+				authed = true
+				reply = "OK"
+			} else {
+				reply = Error("WRONGPASS invalid password")
+			}
+		case !authed:
+			reply = Error("NOAUTH Authentication required.")
+		case cmd == "SELECT":
+			// databases are per connection
+			if len(argv) != 2 {
+				reply = wrongArgs(cmd)
+			} else if n, err := strconv.Atoi(argv[1]); err != nil || n < 0 || n > 15 {
+				reply = Error("ERR DB index is out of range")
+			} else {
+				db, reply = n, "OK"
+			}
+		default:
+			reply = s.exec(db, cmd, argv[1:])
+		}
+		writeValue(bw, reply)
+		if bw.Flush() != nil {
+			return
+		}
+	}
+}
+
+func wrongArgs(cmd string) Error {
+	return Error("ERR wrong number of arguments for '" + strings.ToLower(cmd) + "' command")
+}
+
+// exec run command against data of database db
+func (s *Server) exec(db int, cmd string, args []string) interface{} {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	data := s.dbs[db]
+	if data == nil {
+		data = make(map[string]entry)
+		s.dbs[db] = data
+	}
+	now := time.Now()
+	get := func(key string) (entry, bool) {
+		e, ok := data[key]
+		if ok && e.expired(now) {
+			delete(data, key)
+			return e, false
+		}
+		return e, ok
+	}
+	switch cmd {
+	case "PING":
+		if len(args) > 0 {
+			return []byte(args[0])
+		}
+		return "PONG"
+	case "GET":
+		if len(args) != 1 {
+			return wrongArgs(cmd)
+		}
+		if e, ok := get(args[0]); ok {
+			return e.value
+		}
+		return nil
+	case "SET", "SETEX":
+		var key, value string
+		var ttl time.Duration
+		if cmd == "SETEX" {
+			if len(args) != 3 {
+				return wrongArgs(cmd)
+			}
+			sec, err := strconv.Atoi(args[1])
+			if err != nil || sec <= 0 {
+				return Error("ERR invalid expire time in 'setex' command")
+			}
+			key, value, ttl = args[0], args[2], time.Duration(sec)*time.Second
+		} else {
+			if len(args) != 2 && len(args) != 4 {
+				return wrongArgs(cmd)
+			}
+			key, value = args[0], args[1]
+			if len(args) == 4 {
+				n, err := strconv.Atoi(args[3])
+				if err != nil || n <= 0 {
+					return Error("ERR invalid expire time in 'set' command")
+				}
+				switch strings.ToUpper(args[2]) {
+				case "EX":
+					ttl = time.Duration(n) * time.Second
+				case "PX":
+					ttl = time.Duration(n) * time.Millisecond
+				default:
+					return Error("ERR syntax error")
+				}
+			}
+		}
+		e := entry{value: []byte(value)}
+		if ttl > 0 {
+			e.expires = now.Add(ttl)
+		}
+		data[key] = e
+		return "OK"
+	case "DEL", "EXISTS":
+		if len(args) == 0 {
+			return wrongArgs(cmd)
+		}
+		var n int64
+		for _, key := range args {
+			if _, ok := get(key); ok {
+				n++
+				if cmd == "DEL" {
+					delete(data, key)
+				}
+			}
+		}
+		return n
+	case "EXPIRE":
+		if len(args) != 2 {
+			return wrongArgs(cmd)
+		}
+		sec, err := strconv.Atoi(args[1])
+		if err != nil {
+			return Error("ERR value is not an integer or out of range")
+		}
+		e, ok := get(args[0])
+		if !ok {
+			return int64(0)
+		}
+		e.expires = now.Add(time.Duration(sec) * time.Second)
+		data[args[0]] = e
+		return int64(1)
+	case "TTL":
+		if len(args) != 1 {
+			return wrongArgs(cmd)
+		}
+		e, ok := get(args[0])
+		if !ok {
+			return int64(-2)
+		}
+		if e.expires.IsZero() {
+			return int64(-1)
+		}
+		return int64(e.expires.Sub(now).Round(time.Second) / time.Second)
+	case "RENAME", "RENAMENX":
+		if len(args) != 2 {
+			return wrongArgs(cmd)
+		}
+		e, ok := get(args[0])
+		if !ok {
+			return Error("ERR no such key")
+		}
+		if cmd == "RENAMENX" {
+			if _, exists := get(args[1]); exists {
+				return int64(0)
+			}
+		}
+		delete(data, args[0])
+		data[args[1]] = e
+		if cmd == "RENAMENX" {
+			return int64(1)
+		}
+		return "OK"
+	case "KEYS", "SCAN":
+		pattern := "*"
+		if cmd == "KEYS" {
+			if len(args) != 1 {
+				return wrongArgs(cmd)
+			}
+			pattern = args[0]
+		} else {
+			// whole keyspace is returned in one iteration
+			for i := 1; i+1 < len(args); i += 2 {
+				if strings.ToUpper(args[i]) == "MATCH" {
+					pattern = args[i+1]
+				}
+			}
+		}
+		keys := []interface{}{}
+		for key := range data {
+			if _, ok := get(key); !ok {
+				continue
+			}
+			if ok, _ := path.Match(pattern, key); ok {
+				keys = append(keys, []byte(key))
+			}
+		}
+		if cmd == "SCAN" {
+			return []interface{}{[]byte("0"), keys}
+		}
+		return keys
+	case "DBSIZE":
+		var n int64
+		for key := range data {
+			if _, ok := get(key); ok {
+				n++
+			}
+		}
+		return n
+	case "FLUSHDB":
+		s.dbs[db] = make(map[string]entry)
+		return "OK"
+	}
+	return Error("ERR unknown command '" + strings.ToLower(cmd) + "'")
+}
+
+// writeValue write RESP encoded v
+func writeValue(w *bufio.Writer, v interface{}) {
+	switch v := v.(type) {
+	case nil:
+		w.WriteString("$-1\r\n")
+	case string:
+		w.WriteString("+" + v + "\r\n")
+	case Error:
+		w.WriteString("-" + string(v) + "\r\n")
+	case int64:
+		w.WriteString(":" + strconv.FormatInt(v, 10) + "\r\n")
+	case []byte:
+		writeBulk(w, v)
+	case []interface{}:
+		fmt.Fprintf(w, "*%d\r\n", len(v))
+		for _, e := range v {
+			writeValue(w, e)
+		}
+	}
+}
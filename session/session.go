@@ -0,0 +1,129 @@
+// Package session provides session management over pluggable providers
+// Usage:
+//
+//	conf := &session.CookieConfig{ProviderName: "cookie", CookieName: "gonsession", ...}
+//	manager, err := session.NewManager(conf)
+//	go manager.GC()
+//
+// Custom providers are added with Register:
+//
+//	session.Register("mystore", &MyProvider{})
+package session
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync/atomic"
+)
+
+// Store contains all data for one session process with specific id
+type Store interface {
+	Set(ctx context.Context, key string, value interface{}, r *http.Request, w http.ResponseWriter) error // set session value
+	Get(ctx context.Context, key string) interface{}                                                      // get session value
+	Delete(ctx context.Context, key string, r *http.Request, w http.ResponseWriter) error                 // delete session value
+	Flush(ctx context.Context, r *http.Request, w http.ResponseWriter) error                              // delete all data
+	Save(ctx context.Context, rr interface{}, r *http.Request, w http.ResponseWriter) error               // persist session data
+	SessionID(ctx context.Context) string                                                                 // back current sessionID
+}
+
+// Provider contains global session methods and saved SessionStores
+// it can operate a SessionStore by its id
+type Provider interface {
+	SessionInit(ctx context.Context, gclifetime int64, config string) error
+	SessionRead(ctx context.Context, sid string) (Store, error)
+	SessionExist(ctx context.Context, sid string) (bool, error)
+	SessionRegenerate(ctx context.Context, oldsid, sid string) (Store, error)
+	SessionDestroy(ctx context.Context, sid string) error
+	SessionAll(ctx context.Context) int // get all active session
+	SessionGC(ctx context.Context)
+}
+
+// Locker is implemented by providers which can serialize
+// requests of one session across goroutines or processes.
+// SessionLock blocks until lock of sid is taken or ctx is done,
+// returned func releases it
+type Locker interface {
+	SessionLock(ctx context.Context, sid string) (unlock func(), err error)
+}
+
+// ErrLockTimeout is returned when session lock is not taken in time
+var ErrLockTimeout = errors.New("session: lock timeout")
+
+var provides = make(map[string]Provider)
+
+// Register makes a session provide available by the provided name.
+// If Register is called twice with the same name or if driver is nil,
+// it panics.
+func Register(name string, provide Provider) {
+	if provide == nil {
+		panic("session: Register provide is nil")
+	}
+	if _, dup := provides[name]; dup {
+		panic("session: Register called twice for provider " + name)
+	}
+	provides[name] = provide
+}
+
+// GetProvider returns registered provider by name
+func GetProvider(name string) (Provider, error) {
+	provider, ok := provides[name]
+	if !ok {
+		return nil, fmt.Errorf("session: unknown provide %q (forgotten import?)", name)
+	}
+	return provider, nil
+}
+
+// trackedStore wraps Store returned by Manager
+// and records whether session was changed since it was read
+type trackedStore struct {
+	Store
+	dirty    int32
+	readOnly bool   // no lock held, changes are saved under short lock
+	unlock   func() // releases session lock
+}
+
+// staleStore is implemented by stores read in an outdated format,
+// Manager reports them dirty so they are saved again
+type staleStore interface {
+	stale() bool
+}
+
+func (ts *trackedStore) Set(ctx context.Context, key string, value interface{}, r *http.Request, w http.ResponseWriter) error {
+	atomic.StoreInt32(&ts.dirty, 1)
+	return ts.Store.Set(ctx, key, value, r, w)
+}
+
+func (ts *trackedStore) Delete(ctx context.Context, key string, r *http.Request, w http.ResponseWriter) error {
+	atomic.StoreInt32(&ts.dirty, 1)
+	return ts.Store.Delete(ctx, key, r, w)
+}
+
+func (ts *trackedStore) Flush(ctx context.Context, r *http.Request, w http.ResponseWriter) error {
+	atomic.StoreInt32(&ts.dirty, 1)
+	return ts.Store.Flush(ctx, r, w)
+}
+
+// Save persist session and mark it clean
+func (ts *trackedStore) Save(ctx context.Context, rr interface{}, r *http.Request, w http.ResponseWriter) error {
+	atomic.StoreInt32(&ts.dirty, 0)
+	return ts.Store.Save(ctx, rr, r, w)
+}
+
+// Dirty report whether session st was changed since it was read or saved.
+// Stores not returned by Manager are always reported dirty
+func Dirty(st Store) bool {
+	if ts, ok := st.(*trackedStore); ok {
+		return atomic.LoadInt32(&ts.dirty) == 1
+	}
+	return st != nil
+}
+
+// Unwrap return provider store of st returned by Manager
+func Unwrap(st Store) Store {
+	if ts, ok := st.(*trackedStore); ok {
+		return ts.Store
+	}
+	return st
+}
@@ -2,6 +2,7 @@ package session
 
 import (
 	"bytes"
+	"crypto/aes"
 	"crypto/cipher"
 	"crypto/hmac"
 	"crypto/rand"
@@ -14,6 +15,7 @@ import (
 	"github.com/mellowarex/gon/utils"
 	"io"
 	"strconv"
+	"strings"
 	"time"
 )
 
@@ -63,32 +65,133 @@ func generateRandomKey(strength int) []byte {
 	return k
 }
 
-func encodeCookie(block cipher.Block, hashKey, name string, value map[string]interface{}) (string, error) {
-	var err error
-	var b []byte
+// Keyring --------------------------------------------------------------------
+
+// keyring holds cookie keys newest first
+// cookies are sealed with the newest key and opened with any of them,
+// so keys can be rotated by prepending a new one and dropping the
+// oldest once cookies sealed with it expired.
+type keyring struct {
+	keys []ringKey
+}
+
+type ringKey struct {
+	id   string
+	aead cipher.AEAD
+}
+
+// newKeyring build keyring from keys newest first
+// AES-256-GCM key of each entry is HMAC-SHA256(hashKey, blockKey)
+// so both secrets are needed to open a cookie
+func newKeyring(keys []CookieKey) (*keyring, error) {
+	if len(keys) == 0 {
+		return nil, errors.New("session: empty cookie keyring")
+	}
+	ring := &keyring{}
+	seen := make(map[string]bool)
+	for _, k := range keys {
+		if k.ID == "" || strings.Contains(k.ID, "|") {
+			return nil, errors.New("session: invalid cookie key id " + strconv.Quote(k.ID))
+		}
+		if seen[k.ID] {
+			return nil, errors.New("session: duplicate cookie key id " + k.ID)
+		}
+		if k.HashKey == "" || k.BlockKey == "" {
+			return nil, errors.New("session: cookie key " + k.ID + " needs hashKey and blockKey")
+		}
+		seen[k.ID] = true
+		h := hmac.New(sha256.New, []byte(k.HashKey))
+		h.Write([]byte(k.BlockKey))
+		block, err := aes.NewCipher(h.Sum(nil))
+		if err != nil {
+			return nil, err
+		}
+		aead, err := cipher.NewGCM(block)
+		if err != nil {
+			return nil, err
+		}
+		ring.keys = append(ring.keys, ringKey{id: k.ID, aead: aead})
+	}
+	return ring, nil
+}
+
+// find key by id
+func (ring *keyring) find(id string) (ringKey, bool) {
+	for _, k := range ring.keys {
+		if k.id == id {
+			return k, true
+		}
+	}
+	return ringKey{}, false
+}
+
+// encodeCookie seal value with newest key of ring
+// cookie is base64 of "keyid|date|nonce+ciphertext",
+// name, key id and date are authenticated as additional data
+func encodeCookie(ring *keyring, name string, value map[string]interface{}) (string, error) {
 	// 1. EncodeGob.
-	if b, err = EncodeGob(value); err != nil {
+	b, err := EncodeGob(value)
+	if err != nil {
 		return "", err
 	}
-	// 2. Encrypt (optional).
-	if b, err = encrypt(block, b); err != nil {
-		return "", err
+	// 2. Seal with newest key.
+	k := ring.keys[0]
+	nonce := generateRandomKey(k.aead.NonceSize())
+	header := fmt.Sprintf("%s|%d|", k.id, time.Now().UTC().Unix())
+	sealed := k.aead.Seal(nonce, nonce, b, []byte(name+"|"+header))
+	// 3. Encode to base64.
+	return string(encode(append([]byte(header), sealed...))), nil
+}
+
+// decodeCookie open value sealed by encodeCookie with key named in it
+func decodeCookie(ring *keyring, name, value string, gcmaxlifetime int64) (map[string]interface{}, error) {
+	// 1. Decode from base64.
+	b, err := decode([]byte(value))
+	if err != nil {
+		return nil, err
 	}
-	b = encode(b)
-	// 3. Create MAC for "name|date|value". Extra pipe to be used later.
-	b = []byte(fmt.Sprintf("%s|%d|%s|", name, time.Now().UTC().Unix(), b))
-	h := hmac.New(sha256.New, []byte(hashKey))
-	h.Write(b)
-	sig := h.Sum(nil)
-	// Append mac, remove name.
-	b = append(b, sig...)[len(name)+1:]
-	// 4. Encode to base64.
-	b = encode(b)
-	// Done.
-	return string(b), nil
+	// 2. Split "keyid|date|sealed".
+	parts := bytes.SplitN(b, []byte("|"), 3)
+	if len(parts) != 3 {
+		return nil, errors.New("Decode: invalid value format")
+	}
+	k, ok := ring.find(string(parts[0]))
+	if !ok {
+		return nil, errors.New("Decode: unknown key id")
+	}
+	// 3. Verify date ranges.
+	var t1 int64
+	if t1, err = strconv.ParseInt(string(parts[1]), 10, 64); err != nil {
+		return nil, errors.New("Decode: invalid timestamp")
+	}
+	t2 := time.Now().UTC().Unix()
+	if t1 > t2 {
+		return nil, errors.New("Decode: timestamp is too new")
+	}
+	if gcmaxlifetime > 0 && t1 < t2-gcmaxlifetime {
+		return nil, errors.New("Decode: expired timestamp")
+	}
+	// 4. Open, authenticating name, key id and date.
+	sealed := parts[2]
+	if len(sealed) < k.aead.NonceSize() {
+		return nil, errors.New("Decode: the value is not valid")
+	}
+	header := b[:len(b)-len(sealed)]
+	nonce := sealed[:k.aead.NonceSize()]
+	plain, err := k.aead.Open(nil, nonce, sealed[len(nonce):], append([]byte(name+"|"), header...))
+	if err != nil {
+		return nil, errors.New("Decode: the value is not valid")
+	}
+	// 5. DecodeGob.
+	return DecodeGob(plain)
 }
 
-func decodeCookie(block cipher.Block, hashKey, name, value string, gcmaxlifetime int64) (map[string]interface{}, error) {
+// Legacy ---------------------------------------------------------------------
+
+// decodeLegacyCookie open value encoded by gon before keyring,
+// base64 of "date|base64(iv+ciphertext)|mac" where mac is HMAC-SHA256(hashKey)
+// of "name|date|value|" and ciphertext is AES-CTR with block
+func decodeLegacyCookie(block cipher.Block, hashKey, name, value string, gcmaxlifetime int64) (map[string]interface{}, error) {
 	// 1. Decode from base64.
 	b, err := decode([]byte(value))
 	if err != nil {
@@ -99,12 +202,10 @@ func decodeCookie(block cipher.Block, hashKey, name, value string, gcmaxlifetime
 	if len(parts) != 3 {
 		return nil, errors.New("Decode: invalid value format")
 	}
-
-	b = append([]byte(name+"|"), b[:len(b)-len(parts[2])]...)
 	h := hmac.New(sha256.New, []byte(hashKey))
-	h.Write(b)
-	sig := h.Sum(nil)
-	if len(sig) != len(parts[2]) || subtle.ConstantTimeCompare(sig, parts[2]) != 1 {
+	h.Write([]byte(name + "|"))
+	h.Write(b[:len(b)-len(parts[2])])
+	if subtle.ConstantTimeCompare(h.Sum(nil), parts[2]) != 1 {
 		return nil, errors.New("Decode: the value is not valid")
 	}
 	// 3. Verify date ranges.
@@ -116,60 +217,22 @@ func decodeCookie(block cipher.Block, hashKey, name, value string, gcmaxlifetime
 	if t1 > t2 {
 		return nil, errors.New("Decode: timestamp is too new")
 	}
-	if t1 < t2-gcmaxlifetime {
+	if gcmaxlifetime > 0 && t1 < t2-gcmaxlifetime {
 		return nil, errors.New("Decode: expired timestamp")
 	}
-	// 4. Decrypt (optional).
+	// 4. Decrypt, iv is prepended to ciphertext.
 	b, err = decode(parts[1])
 	if err != nil {
 		return nil, err
 	}
-	if b, err = decrypt(block, b); err != nil {
-		return nil, err
+	size := block.BlockSize()
+	if len(b) <= size {
+		return nil, errors.New("Decode: the value could not be decrypted")
 	}
+	plain := make([]byte, len(b)-size)
+	cipher.NewCTR(block, b[:size]).XORKeyStream(plain, b[size:])
 	// 5. DecodeGob.
-	dst, err := DecodeGob(b)
-	if err != nil {
-		return nil, err
-	}
-	return dst, nil
-}
-
-// Encryption -----------------------------------------------------------------
-
-// encrypt encrypts a value using the given block in counter mode.
-//
-// A random initialization vector (http://goo.gl/zF67k) with the length of the
-// block size is prepended to the resulting ciphertext.
-func encrypt(block cipher.Block, value []byte) ([]byte, error) {
-	iv := generateRandomKey(block.BlockSize())
-	if iv == nil {
-		return nil, errors.New("encrypt: failed to generate random iv")
-	}
-	// Encrypt it.
-	stream := cipher.NewCTR(block, iv)
-	stream.XORKeyStream(value, value)
-	// Return iv + ciphertext.
-	return append(iv, value...), nil
-}
-
-// decrypt decrypts a value using the given block in counter mode.
-//
-// The value to be decrypted must be prepended by a initialization vector
-// (http://goo.gl/zF67k) with the length of the block size.
-func decrypt(block cipher.Block, value []byte) ([]byte, error) {
-	size := block.BlockSize()
-	if len(value) > size {
-		// Extract iv.
-		iv := value[:size]
-		// Extract ciphertext.
-		value = value[size:]
-		// Decrypt it.
-		stream := cipher.NewCTR(block, iv)
-		stream.XORKeyStream(value, value)
-		return value, nil
-	}
-	return nil, errors.New("decrypt: the value could not be decrypted")
+	return DecodeGob(plain)
 }
 
 // Encoding -------------------------------------------------------------------
@@ -0,0 +1,449 @@
+package session
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"io"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeDB is the state of one database of fakeDriver
+// it understands the statements of SQLProvider only
+type fakeDB struct {
+	lock    sync.Mutex
+	queries []string
+	rows    map[string]fakeRow
+	lockRow map[string]bool
+	held    map[string]chan struct{} // lock rows selected FOR UPDATE
+}
+
+type fakeRow struct {
+	data   []byte
+	expiry int64
+}
+
+type fakeDriver struct {
+	lock sync.Mutex
+	dbs  map[string]*fakeDB
+}
+
+var fakeSQL = &fakeDriver{dbs: make(map[string]*fakeDB)}
+
+func init() {
+	sql.Register("sessionfake", fakeSQL)
+}
+
+// openFakeDB returns db of new fake database named after test
+func openFakeDB(t *testing.T) (*sql.DB, *fakeDB) {
+	t.Helper()
+	fdb := &fakeDB{rows: make(map[string]fakeRow), lockRow: make(map[string]bool), held: make(map[string]chan struct{})}
+	fakeSQL.lock.Lock()
+	fakeSQL.dbs[t.Name()] = fdb
+	fakeSQL.lock.Unlock()
+	db, err := sql.Open("sessionfake", t.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+	return db, fdb
+}
+
+// Open implements driver.Driver
+func (d *fakeDriver) Open(name string) (driver.Conn, error) {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+	db, ok := d.dbs[name]
+	if !ok {
+		return nil, errors.New("fake: unknown database " + name)
+	}
+	return &fakeConn{db: db}, nil
+}
+
+type fakeConn struct {
+	db   *fakeDB
+	held []string // lock rows of open transaction
+}
+
+func (c *fakeConn) Prepare(query string) (driver.Stmt, error) {
+	return nil, errors.New("fake: Prepare is not supported")
+}
+
+func (c *fakeConn) Close() error { return nil }
+
+func (c *fakeConn) Begin() (driver.Tx, error) { return c, nil }
+
+// Commit and Rollback release lock rows of transaction
+func (c *fakeConn) Commit() error {
+	c.db.lock.Lock()
+	defer c.db.lock.Unlock()
+	for _, key := range c.held {
+		close(c.db.held[key])
+		delete(c.db.held, key)
+	}
+	c.held = nil
+	return nil
+}
+
+func (c *fakeConn) Rollback() error { return c.Commit() }
+
+func (c *fakeConn) ExecContext(ctx context.Context, query string, nargs []driver.NamedValue) (driver.Result, error) {
+	args := fakeArgs(nargs)
+	db := c.db
+	db.lock.Lock()
+	defer db.lock.Unlock()
+	db.queries = append(db.queries, query)
+	var n int64
+	switch {
+	case strings.HasPrefix(query, "CREATE "):
+	case strings.Contains(query, "_lock (session_key) VALUES"):
+		db.lockRow[args[0].(string)] = true
+	case strings.HasPrefix(query, "DELETE FROM") && strings.Contains(query, "_lock WHERE session_key NOT IN"):
+		for key := range db.lockRow {
+			if _, ok := db.rows[key]; !ok {
+				delete(db.lockRow, key)
+				n++
+			}
+		}
+	case strings.HasPrefix(query, "INSERT INTO"):
+		key := args[0].(string)
+		if _, ok := db.rows[key]; ok && !strings.Contains(query, " ON ") {
+			return nil, errors.New("fake: duplicate key " + key)
+		}
+		db.rows[key] = fakeRow{args[1].([]byte), args[2].(int64)}
+		n = 1
+	case strings.HasPrefix(query, "UPDATE") && strings.Contains(query, "SET session_data"):
+		key := args[2].(string)
+		if _, ok := db.rows[key]; ok {
+			db.rows[key] = fakeRow{args[0].([]byte), args[1].(int64)}
+			n = 1
+		}
+	case strings.HasPrefix(query, "UPDATE") && strings.Contains(query, "SET session_key"):
+		sid, oldsid := args[0].(string), args[1].(string)
+		if row, ok := db.rows[oldsid]; ok {
+			if _, exists := db.rows[sid]; exists {
+				return nil, errors.New("fake: duplicate key " + sid)
+			}
+			delete(db.rows, oldsid)
+			db.rows[sid] = row
+			n = 1
+		}
+	case strings.HasPrefix(query, "DELETE FROM") && strings.Contains(query, "session_expiry <"):
+		for key, row := range db.rows {
+			if row.expiry < args[0].(int64) {
+				delete(db.rows, key)
+				n++
+			}
+		}
+	case strings.HasPrefix(query, "DELETE FROM"):
+		if _, ok := db.rows[args[0].(string)]; ok {
+			delete(db.rows, args[0].(string))
+			n = 1
+		}
+	default:
+		return nil, errors.New("fake: unexpected statement " + query)
+	}
+	return driver.RowsAffected(n), nil
+}
+
+func (c *fakeConn) QueryContext(ctx context.Context, query string, nargs []driver.NamedValue) (driver.Rows, error) {
+	args := fakeArgs(nargs)
+	db := c.db
+	db.lock.Lock()
+	db.queries = append(db.queries, query)
+	db.lock.Unlock()
+	if strings.HasSuffix(query, " FOR UPDATE") {
+		return c.selectForUpdate(ctx, args[0].(string))
+	}
+	db.lock.Lock()
+	defer db.lock.Unlock()
+	switch {
+	case strings.HasPrefix(query, "SELECT session_data, session_expiry"):
+		if row, ok := db.rows[args[0].(string)]; ok {
+			return &fakeRows{cols: []string{"session_data", "session_expiry"}, values: [][]driver.Value{{row.data, row.expiry}}}, nil
+		}
+		return &fakeRows{cols: []string{"session_data", "session_expiry"}}, nil
+	case strings.HasPrefix(query, "SELECT session_expiry"):
+		if row, ok := db.rows[args[0].(string)]; ok {
+			return &fakeRows{cols: []string{"session_expiry"}, values: [][]driver.Value{{row.expiry}}}, nil
+		}
+		return &fakeRows{cols: []string{"session_expiry"}}, nil
+	case strings.HasPrefix(query, "SELECT COUNT(*)"):
+		var n int64
+		for _, row := range db.rows {
+			if row.expiry >= args[0].(int64) {
+				n++
+			}
+		}
+		return &fakeRows{cols: []string{"count"}, values: [][]driver.Value{{n}}}, nil
+	}
+	return nil, errors.New("fake: unexpected query " + query)
+}
+
+// selectForUpdate waits until lock row of key is free and holds it until transaction ends
+func (c *fakeConn) selectForUpdate(ctx context.Context, key string) (driver.Rows, error) {
+	db := c.db
+	for {
+		db.lock.Lock()
+		if !db.lockRow[key] {
+			db.lock.Unlock()
+			return &fakeRows{cols: []string{"session_key"}}, nil
+		}
+		ch, busy := db.held[key]
+		if !busy {
+			db.held[key] = make(chan struct{})
+			c.held = append(c.held, key)
+			db.lock.Unlock()
+			return &fakeRows{cols: []string{"session_key"}, values: [][]driver.Value{{key}}}, nil
+		}
+		db.lock.Unlock()
+		select {
+		case <-ch:
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+}
+
+func fakeArgs(nargs []driver.NamedValue) []interface{} {
+	args := make([]interface{}, len(nargs))
+	for i, a := range nargs {
+		args[i] = a.Value
+	}
+	return args
+}
+
+type fakeRows struct {
+	cols   []string
+	values [][]driver.Value
+}
+
+func (r *fakeRows) Columns() []string { return r.cols }
+
+func (r *fakeRows) Close() error { return nil }
+
+func (r *fakeRows) Next(dest []driver.Value) error {
+	if len(r.values) == 0 {
+		return io.EOF
+	}
+	copy(dest, r.values[0])
+	r.values = r.values[1:]
+	return nil
+}
+
+// newSQLTest returns provider of driver dialect over fake database
+func newSQLTest(t *testing.T, driverName string, maxlifetime int64) (*SQLProvider, *fakeDB) {
+	t.Helper()
+	db, fdb := openFakeDB(t)
+	sp := NewSQLProvider(db, driverName, "")
+	if err := sp.SessionInit(context.Background(), maxlifetime, ""); err != nil {
+		t.Fatal(err)
+	}
+	return sp, fdb
+}
+
+func TestSQLDialects(t *testing.T) {
+	tests := []struct {
+		driver string
+		want   []string // statements containing these
+		lock   bool
+	}{
+		{"postgres", []string{
+			"CREATE TABLE IF NOT EXISTS session (session_key VARCHAR(128) NOT NULL PRIMARY KEY, session_data BYTEA, session_expiry BIGINT NOT NULL)",
+			"CREATE INDEX IF NOT EXISTS session_expiry_idx ON session (session_expiry)",
+			"CREATE TABLE IF NOT EXISTS session_lock",
+			"INSERT INTO session (session_key, session_data, session_expiry) VALUES ($1, $2, $3) ON CONFLICT (session_key) DO UPDATE",
+			"WHERE session_key = $1",
+		}, true},
+		{"pgx", []string{"BYTEA", "VALUES ($1, $2, $3) ON CONFLICT"}, true},
+		{"mysql", []string{
+			"session_data MEDIUMBLOB, session_expiry BIGINT NOT NULL, INDEX session_expiry_idx (session_expiry))",
+			"CREATE TABLE IF NOT EXISTS session_lock",
+			"VALUES (?, ?, ?) ON DUPLICATE KEY UPDATE",
+		}, true},
+		{"sqlite3", []string{"session_data BLOB", "VALUES (?, ?, ?) ON CONFLICT (session_key)"}, false},
+		{"sqlite", []string{"session_data BLOB", "VALUES (?, ?, ?) ON CONFLICT (session_key)"}, false},
+		// unknown drivers update then insert
+		{"odbc", []string{
+			"session_data BLOB",
+			"UPDATE session SET session_data = ?, session_expiry = ? WHERE session_key = ?",
+			"INSERT INTO session (session_key, session_data, session_expiry) VALUES (?, ?, ?)",
+		}, false},
+	}
+	ctx := context.Background()
+	for _, tt := range tests {
+		t.Run(tt.driver, func(t *testing.T) {
+			sp, fdb := newSQLTest(t, tt.driver, 3600)
+			st, err := sp.SessionRead(ctx, "abc")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := st.Save(ctx, nil, nil, nil); err != nil {
+				t.Fatal(err)
+			}
+			unlock, err := sp.SessionLock(ctx, "abc")
+			if err != nil {
+				t.Fatal(err)
+			}
+			unlock()
+			all := strings.Join(fdb.queries, "\n")
+			for _, want := range tt.want {
+				if !strings.Contains(all, want) {
+					t.Errorf("no statement contains %q in:\n%s", want, all)
+				}
+			}
+			if got := strings.Contains(all, "FOR UPDATE"); got != tt.lock {
+				t.Errorf("locking = %v, want %v", got, tt.lock)
+			}
+		})
+	}
+}
+
+func TestSQLProviderReadSave(t *testing.T) {
+	ctx := context.Background()
+	for _, driverName := range []string{"postgres", "odbc"} {
+		t.Run(driverName, func(t *testing.T) {
+			sp, fdb := newSQLTest(t, driverName, 3600)
+
+			st, err := sp.SessionRead(ctx, "abc")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(fdb.rows) != 0 {
+				t.Fatal("reading new session inserted row")
+			}
+			if ok, _ := sp.SessionExist(ctx, "abc"); ok {
+				t.Fatal("unsaved session exists")
+			}
+
+			st.Set(ctx, "user", "alice", nil, nil)
+			if err := st.Save(ctx, nil, nil, nil); err != nil {
+				t.Fatal(err)
+			}
+			st.Set(ctx, "user", "bob", nil, nil)
+			if err := st.Save(ctx, nil, nil, nil); err != nil {
+				t.Fatal(err)
+			}
+			if ok, _ := sp.SessionExist(ctx, "abc"); !ok {
+				t.Fatal("saved session does not exist")
+			}
+			st, err = sp.SessionRead(ctx, "abc")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if v := st.Get(ctx, "user"); v != "bob" {
+				t.Fatalf("user = %v, want bob", v)
+			}
+			if n := sp.SessionAll(ctx); n != 1 {
+				t.Fatalf("SessionAll = %d, want 1", n)
+			}
+		})
+	}
+}
+
+func TestSQLProviderLock(t *testing.T) {
+	ctx := context.Background()
+	sp, _ := newSQLTest(t, "postgres", 3600)
+
+	unlock, err := sp.SessionLock(ctx, "abc")
+	if err != nil {
+		t.Fatal(err)
+	}
+	wait, cancel := context.WithTimeout(ctx, 50*time.Millisecond)
+	defer cancel()
+	if _, err := sp.SessionLock(wait, "abc"); err != context.DeadlineExceeded {
+		t.Fatalf("second lock err = %v, want deadline exceeded", err)
+	}
+	other, err := sp.SessionLock(ctx, "other")
+	if err != nil {
+		t.Fatal(err)
+	}
+	other()
+
+	locked := make(chan func())
+	go func() {
+		unlock, err := sp.SessionLock(ctx, "abc")
+		if err != nil {
+			t.Error(err)
+		}
+		locked <- unlock
+	}()
+	select {
+	case <-locked:
+		t.Fatal("lock taken while held")
+	case <-time.After(20 * time.Millisecond):
+	}
+	unlock()
+	select {
+	case unlock := <-locked:
+		unlock()
+	case <-time.After(time.Second):
+		t.Fatal("lock not taken after release")
+	}
+}
+
+func TestSQLProviderRegenerate(t *testing.T) {
+	ctx := context.Background()
+	sp, fdb := newSQLTest(t, "postgres", 3600)
+
+	st, _ := sp.SessionRead(ctx, "old")
+	st.Set(ctx, "user", "alice", nil, nil)
+	st.Save(ctx, nil, nil, nil)
+
+	st, err := sp.SessionRegenerate(ctx, "old", "new")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if st.SessionID(ctx) != "new" || st.Get(ctx, "user") != "alice" {
+		t.Fatalf("regenerated session %s has user %v", st.SessionID(ctx), st.Get(ctx, "user"))
+	}
+	if _, ok := fdb.rows["old"]; ok {
+		t.Fatal("old session row still exists")
+	}
+
+	// session which was never saved regenerates empty
+	st, err = sp.SessionRegenerate(ctx, "unsaved", "fresh")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if st.Get(ctx, "user") != nil {
+		t.Fatal("regenerated unsaved session has values")
+	}
+}
+
+func TestSQLProviderDestroyGC(t *testing.T) {
+	ctx := context.Background()
+	sp, fdb := newSQLTest(t, "postgres", 3600)
+
+	for _, sid := range []string{"live", "destroyed", "expired"} {
+		st, _ := sp.SessionRead(ctx, sid)
+		st.Save(ctx, nil, nil, nil)
+		unlock, err := sp.SessionLock(ctx, sid)
+		if err != nil {
+			t.Fatal(err)
+		}
+		unlock()
+	}
+	if err := sp.SessionDestroy(ctx, "destroyed"); err != nil {
+		t.Fatal(err)
+	}
+	fdb.rows["expired"] = fakeRow{fdb.rows["expired"].data, time.Now().Unix() - 1}
+	if ok, _ := sp.SessionExist(ctx, "expired"); ok {
+		t.Fatal("expired session exists")
+	}
+	if n := sp.SessionAll(ctx); n != 1 {
+		t.Fatalf("SessionAll = %d, want 1", n)
+	}
+
+	sp.SessionGC(ctx)
+	if len(fdb.rows) != 1 || len(fdb.lockRow) != 1 {
+		t.Fatalf("after GC rows %v, lock rows %v, want live only", fdb.rows, fdb.lockRow)
+	}
+	if _, ok := fdb.rows["live"]; !ok || !fdb.lockRow["live"] {
+		t.Fatal("GC removed live session")
+	}
+}
@@ -1,6 +1,9 @@
 package gon
 
 import (
+	"fmt"
+	"io"
+	"io/fs"
 	"os"
 	"path"
 	"path/filepath"
@@ -11,6 +14,7 @@ import (
 	"time"
 	"bytes"
 	"sync"
+	"mime"
 
 	"github.com/mellowarex/gon/logs"
 	"github.com/mellowarex/gon/context"
@@ -19,6 +23,43 @@ import (
 )
 
 var errNotStaticRequest = errors.New("request not a static file request")
+
+// staticFS holds file systems served by StaticFS keyed by url prefix
+var staticFS = make(map[string]fs.FS)
+
+// StaticFS serves files of fsys under url prefix,
+// like StaticDir does for disk directories. Usage with embed:
+//
+//	//go:embed public
+//	var public embed.FS
+//
+//	sub, _ := fs.Sub(public, "public")
+//	gon.StaticFS("/static", sub)
+//
+// In development StaticDir on disk is searched first
+// so edits are served without rebuilding.
+func StaticFS(prefix string, fsys fs.FS) {
+	if !strings.HasPrefix(prefix, "/") {
+		prefix = "/" + prefix
+	}
+	if len(prefix) > 1 {
+		prefix = strings.TrimRight(prefix, "/")
+	}
+	staticFS[prefix] = fsys
+}
+
+// diskFS opens files by their disk path
+type diskFS struct{}
+
+func (diskFS) Open(name string) (fs.File, error) {
+	return os.Open(name)
+}
+
+// isDisk reports whether fsys is the disk
+func isDisk(fsys fs.FS) bool {
+	_, ok := fsys.(diskFS)
+	return ok
+}
 // find static file
 // if file is not found do nothing
 // controller will respond with 404 not found
@@ -28,7 +69,7 @@ func serveStaticRoutes(ctx *context.Context) {
 		return
 	}
 
-	forbidden, filepath, fileInfo, err := lookupFile(ctx)
+	forbidden, fsys, filepath, fileInfo, err := lookupFile(ctx)
 	if err == errNotStaticRequest {
 		return
 	}
@@ -56,14 +97,33 @@ func serveStaticRoutes(ctx *context.Context) {
 				redirectURL = redirectURL + "?" + ctx.Request.URL.RawQuery
 			}
 			ctx.Redirect(302, redirectURL)
-		} else {
+		} else if isDisk(fsys) {
 			// serveFile will list dir
 			http.ServeFile(ctx.ResponseWriter, ctx.Request, filepath)
+		} else {
+			exception("403", ctx)
 		}
 		return
-	} else if fileInfo.Size() > int64(GConfig.StaticCacheFileSize) {
+	}
+
+	// fingerprinted url of asset never changes, whichever way file is served
+	if v := ctx.Input.Query("v"); v != "" && v == fileHash(fsys, filepath, fileInfo) {
+		ctx.Output.Header("Cache-Control", "public, max-age=31536000, immutable")
+	}
+
+	// serve precompressed sidecar file such as app.js.br
+	// when client accepts its encoding, whatever size the file has
+	if serveSidecarFile(ctx, fsys, filepath, fileInfo) {
+		return
+	}
+
+	if fileInfo.Size() > staticCacheFileSize() {
 		// over size file serve with http module
-		http.ServeFile(ctx.ResponseWriter, ctx.Request, filepath)
+		if isDisk(fsys) {
+			http.ServeFile(ctx.ResponseWriter, ctx.Request, filepath)
+		} else {
+			serveFSFile(ctx, fsys, filepath)
+		}
 		return
 	}
 
@@ -72,11 +132,12 @@ func serveStaticRoutes(ctx *context.Context) {
 	if enableCompress {
 		acceptEncoding = context.ParseEncoding(ctx.Request)
 	}
-	b, n, sch, reader, err := openFile(filepath, fileInfo, acceptEncoding)
+	b, n, sch, reader, err := openFile(fsys, filepath, fileInfo, acceptEncoding)
 	if err != nil {
 		if GConfig.EnvMode == DEV {
 			logs.Warn("Can't compress the file:", filepath, err)
 		}
+		ctx.ResponseWriter.Header().Del("Cache-Control")
 		http.NotFound(ctx.ResponseWriter, ctx.Request)
 		return
 	}
@@ -93,51 +154,137 @@ func serveStaticRoutes(ctx *context.Context) {
 // lookupFile find file to serve
 // if file is dir, search index.html as default file (MUST NOT BE A DIR also)
 // if index.html does not exist or is a dir, return forbidden response depending on DirectoryIndex
-func lookupFile(ctx *context.Context) (bool, string, os.FileInfo, error) {
-	filePath, fileInfo, err := searchFile(ctx)	
+func lookupFile(ctx *context.Context) (bool, fs.FS, string, os.FileInfo, error) {
+	fsys, filePath, fileInfo, err := searchFile(ctx)
 	if filePath == "" || fileInfo == nil {
-		return false, "", nil, err
+		return false, nil, "", nil, err
 	}
 	if !fileInfo.IsDir() {
-		return false, filePath, fileInfo, err
+		return false, fsys, filePath, fileInfo, err
 	}
 	if requestURL := ctx.Input.URL(); requestURL[len(requestURL)-1] == '/' {
-		ifp := filepath.Join(filePath, "index.html")
-		if ifi, _ := os.Stat(ifp); ifi != nil && ifi.Mode().IsRegular() {
-			return false, ifp, ifi, err
+		ifp := path.Join(filePath, "index.html")
+		if ifi, _ := fs.Stat(fsys, ifp); ifi != nil && ifi.Mode().IsRegular() {
+			return false, fsys, ifp, ifi, err
 		}
 	}
-	return !GConfig.DirectoryIndex, filePath, fileInfo, err
+	return !GConfig.DirectoryIndex, fsys, filePath, fileInfo, err
 }
 
 
 // searchFile search the file by url path
 // if no match is found return not staticRequestErr
-func searchFile(ctx *context.Context) (string, os.FileInfo, error) {
-	requestPath := filepath.ToSlash(filepath.Clean(ctx.Request.URL.Path))
+// disk is searched before StaticFS file systems in development, after them otherwise
+func searchFile(ctx *context.Context) (fs.FS, string, os.FileInfo, error) {
+	return searchPath(filepath.ToSlash(filepath.Clean(ctx.Request.URL.Path)))
+}
+
+// searchPath search static file of url path requestPath
+func searchPath(requestPath string) (fs.FS, string, os.FileInfo, error) {
 	// special processing: favicon.ico|robots.txt 
 	// look for them only in /public folder of webapp
+	// or at root of file system served under "/"
 	if requestPath == "/favicon.ico" || requestPath == "/robots.txt" {
 		filepath := path.Join("public", requestPath[1:])
 		if fi, _ := os.Stat(filepath); fi != nil {
-			return filepath, fi, nil
+			return diskFS{}, filepath, fi, nil
 		}
-		return "", nil, nil
+		if fsys, ok := staticFS["/"]; ok {
+			if fi, _ := fs.Stat(fsys, requestPath[1:]); fi != nil {
+				return fsys, requestPath[1:], fi, nil
+			}
+		}
+		return nil, "", nil, nil
+	}
+
+	if GConfig.EnvMode == DEV {
+		if fsys, filePath, fi, err := searchDisk(requestPath); fi != nil {
+			return fsys, filePath, fi, err
+		}
+		return searchFS(requestPath)
+	}
+	if fsys, filePath, fi, err := searchFS(requestPath); fi != nil {
+		return fsys, filePath, fi, err
+	}
+	return searchDisk(requestPath)
+}
+
+// matchPrefix returns path of requestPath under url prefix
+func matchPrefix(requestPath, prefix string) (string, bool) {
+	if !strings.HasPrefix(requestPath, prefix) {
+		return "", false
+	}
+	if prefix != "/" && len(requestPath) > len(prefix) && requestPath[len(prefix)] != '/' {
+		return "", false
 	}
+	return requestPath[len(prefix):], true
+}
 
+// searchDisk search request path in StaticDir directories
+func searchDisk(requestPath string) (fs.FS, string, os.FileInfo, error) {
 	for prefix, staticDir :=  range GConfig.StaticDir {
-		if !strings.Contains(requestPath, prefix) {
+		rest, ok := matchPrefix(requestPath, prefix)
+		if !ok {
 			continue
 		}
-		if prefix != "/" && len(requestPath) > len(prefix) && requestPath[len(prefix)] != '/' {
+		filePath := path.Join(staticDir, rest) // replace prefix with true server directory file
+		if fi, err := os.Stat(filePath); fi != nil {
+			return diskFS{}, filePath, fi, err
+		}
+	}
+	return nil, "", nil, errNotStaticRequest
+}
+
+// searchFS search request path in StaticFS file systems
+func searchFS(requestPath string) (fs.FS, string, os.FileInfo, error) {
+	for prefix, fsys := range staticFS {
+		rest, ok := matchPrefix(requestPath, prefix)
+		if !ok {
 			continue
 		}
-		filePath := path.Join(staticDir, requestPath[len(prefix):]) // replace prefix with true server directory file
-		if fi, err := os.Stat(filePath); fi != nil {
-			return filePath, fi, err
+		name := strings.TrimPrefix(path.Clean("/"+rest), "/")
+		if name == "" {
+			name = "."
+		}
+		if fi, err := fs.Stat(fsys, name); fi != nil {
+			return fsys, name, fi, err
+		}
+	}
+	return nil, "", nil, errNotStaticRequest
+}
+
+// serveFSFile serves file name of fsys without caching it
+func serveFSFile(ctx *context.Context, fsys fs.FS, name string) {
+	file, err := fsys.Open(name)
+	if err != nil {
+		http.NotFound(ctx.ResponseWriter, ctx.Request)
+		return
+	}
+	defer file.Close()
+	fi, err := file.Stat()
+	if err != nil {
+		http.NotFound(ctx.ResponseWriter, ctx.Request)
+		return
+	}
+	rs, ok := file.(io.ReadSeeker)
+	if !ok {
+		b, err := io.ReadAll(file)
+		if err != nil {
+			exception("500", ctx)
+			return
 		}
+		rs = bytes.NewReader(b)
 	}
-	return "", nil, errNotStaticRequest
+	http.ServeContent(ctx.ResponseWriter, ctx.Request, name, fi.ModTime(), rs)
+}
+
+// cacheKey returns static cache key of file name in fsys
+// files of StaticFS are keyed apart from disk paths
+func cacheKey(fsys fs.FS, name string) string {
+	if isDisk(fsys) {
+		return name
+	}
+	return fmt.Sprintf("fs:%p:%s", fsys, name)
 }
 
 type serveContentHolder struct {
@@ -148,6 +295,14 @@ type serveContentHolder struct {
 	encoding   string
 }
 
+// staticFileVariants holds the compressed variants of one static file
+// keyed by encoding, "" is the uncompressed content
+type staticFileVariants struct {
+	modTime    time.Time
+	originSize int64
+	variants   map[string]*serveContentHolder
+}
+
 type serveContentReader struct {
 	*bytes.Reader
 }
@@ -157,7 +312,8 @@ var (
 	lruLock            sync.RWMutex
 )
 
-func openFile(filePath string, fi os.FileInfo, acceptEncoding string) (bool, string, *serveContentHolder, *serveContentReader, error) {
+func openFile(fsys fs.FS, filePath string, fi os.FileInfo, acceptEncoding string) (bool, string, *serveContentHolder, *serveContentReader, error) {
+	key := cacheKey(fsys, filePath)
 	if staticFileLruCache == nil {
 		// avoid lru cache error
 		if GConfig.StaticCacheFileNum >= 1 {
@@ -166,12 +322,8 @@ func openFile(filePath string, fi os.FileInfo, acceptEncoding string) (bool, str
 			staticFileLruCache, _ = lru.New(1)
 		}
 	}
-	mapKey := acceptEncoding + ":" + filePath
 	lruLock.RLock()
-	var mapFile *serveContentHolder
-	if cacheItem, ok := staticFileLruCache.Get(mapKey); ok {
-		mapFile = cacheItem.(*serveContentHolder)
-	}
+	mapFile := lookupVariant(key, fi, acceptEncoding)
 	lruLock.RUnlock()
 	if isOk(mapFile, fi) {
 		reader := &serveContentReader{Reader: bytes.NewReader(mapFile.data)}
@@ -179,11 +331,9 @@ func openFile(filePath string, fi os.FileInfo, acceptEncoding string) (bool, str
 	}
 	lruLock.Lock()
 	defer lruLock.Unlock()
-	if cacheItem, ok := staticFileLruCache.Get(mapKey); ok {
-		mapFile = cacheItem.(*serveContentHolder)
-	}
+	mapFile = lookupVariant(key, fi, acceptEncoding)
 	if !isOk(mapFile, fi) {
-		file, err := os.Open(filePath)
+		file, err := fsys.Open(filePath)
 		if err != nil {
 			return false, "", nil, nil, err
 		}
@@ -195,7 +345,7 @@ func openFile(filePath string, fi os.FileInfo, acceptEncoding string) (bool, str
 		}
 		mapFile = &serveContentHolder{data: bufferWriter.Bytes(), modTime: fi.ModTime(), size: int64(bufferWriter.Len()), originSize: fi.Size(), encoding: n}
 		if isOk(mapFile, fi) {
-			staticFileLruCache.Add(mapKey, mapFile)
+			storeVariant(key, fi, acceptEncoding, mapFile)
 		}
 	}
 
@@ -203,10 +353,116 @@ func openFile(filePath string, fi os.FileInfo, acceptEncoding string) (bool, str
 	return mapFile.encoding != "", mapFile.encoding, mapFile, reader, nil
 }
 
+// lookupVariant returns cached content of file for encoding
+// caller must hold lruLock
+func lookupVariant(filePath string, fi os.FileInfo, encoding string) *serveContentHolder {
+	cacheItem, ok := staticFileLruCache.Get(filePath)
+	if !ok {
+		return nil
+	}
+	sfv := cacheItem.(*staticFileVariants)
+	if sfv.modTime != fi.ModTime() || sfv.originSize != fi.Size() {
+		return nil
+	}
+	return sfv.variants[encoding]
+}
+
+// storeVariant caches content of file for encoding
+// all variants are dropped once the file changes
+// caller must hold lruLock for writing
+func storeVariant(filePath string, fi os.FileInfo, encoding string, mapFile *serveContentHolder) {
+	var sfv *staticFileVariants
+	if cacheItem, ok := staticFileLruCache.Get(filePath); ok {
+		sfv = cacheItem.(*staticFileVariants)
+	}
+	if sfv == nil || sfv.modTime != fi.ModTime() || sfv.originSize != fi.Size() {
+		sfv = &staticFileVariants{
+			modTime:    fi.ModTime(),
+			originSize: fi.Size(),
+			variants:   make(map[string]*serveContentHolder),
+		}
+		staticFileLruCache.Add(filePath, sfv)
+	}
+	sfv.variants[encoding] = mapFile
+}
+
+// precompressedFiles lists sidecar file extensions per content encoding
+// in order of preference
+var precompressedFiles = []struct {
+	encoding string
+	ext      string
+}{
+	{"br", ".br"},
+	{"gzip", ".gz"},
+}
+
+// serveSidecarFile serves precompressed file found next to filePath
+// sidecar file older than filePath is considered stale and ignored
+// returns false if no acceptable sidecar file exists
+func serveSidecarFile(ctx *context.Context, fsys fs.FS, filePath string, fi os.FileInfo) bool {
+	sidecars := make(map[string]string)
+	offers := make([]string, 0, len(precompressedFiles))
+	for _, pf := range precompressedFiles {
+		sfi, err := fs.Stat(fsys, filePath+pf.ext)
+		if err != nil || !sfi.Mode().IsRegular() || sfi.ModTime().Before(fi.ModTime()) {
+			continue
+		}
+		sidecars[pf.encoding] = filePath + pf.ext
+		offers = append(offers, pf.encoding)
+	}
+	if len(offers) == 0 {
+		return false
+	}
+	ctx.ResponseWriter.Header().Add("Vary", "Accept-Encoding")
+	encoding := context.NegotiateEncoding(ctx.Request, offers...)
+	if encoding == "" {
+		return false
+	}
+	file, err := fsys.Open(sidecars[encoding])
+	if err != nil {
+		return false
+	}
+	defer file.Close()
+	sfi, err := file.Stat()
+	if err != nil {
+		return false
+	}
+
+	// content type comes from the original file not the sidecar
+	ctype := mime.TypeByExtension(filepath.Ext(filePath))
+	if ctype == "" {
+		ctype = "application/octet-stream"
+	}
+	ctx.Output.Header("Content-Type", ctype)
+	ctx.Output.Header("Content-Encoding", encoding)
+	rs, ok := file.(io.ReadSeeker)
+	if !ok {
+		b, err := io.ReadAll(file)
+		if err != nil {
+			return false
+		}
+		rs = bytes.NewReader(b)
+	}
+	http.ServeContent(ctx.ResponseWriter, ctx.Request, filePath, sfi.ModTime(), rs)
+	return true
+}
+
+// defaultStaticCacheFileSize is max size of static file kept in memory
+// when StaticCacheFileSize is not configured
+const defaultStaticCacheFileSize = 100 * 1024
+
+// staticCacheFileSize returns max size of static file kept in memory
+func staticCacheFileSize() int64 {
+	if GConfig.StaticCacheFileSize <= 0 {
+		return defaultStaticCacheFileSize
+	}
+	return int64(GConfig.StaticCacheFileSize)
+}
+
 func isOk(s *serveContentHolder, fi os.FileInfo) bool {
 	if s == nil {
 		return false
-	} else if s.size > int64(GConfig.StaticCacheFileSize) {
+	} else if s.size > staticCacheFileSize() {
 		return false
 	}
 	return s.modTime == fi.ModTime() && s.originSize == fi.Size()
@@ -0,0 +1,100 @@
+package gon
+
+import (
+	"bytes"
+	"io/ioutil"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+
+	"github.com/mellowarex/gon/context"
+)
+
+// newStaticTest serves temp dir under /static with default config
+func newStaticTest(t *testing.T, files map[string][]byte) {
+	t.Helper()
+	conf := GConfig
+	GConfig = initGConfig()
+	t.Cleanup(func() { GConfig = conf })
+	dir := t.TempDir()
+	for name, data := range files {
+		if err := ioutil.WriteFile(filepath.Join(dir, name), data, 0600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	GConfig.StaticDir = map[string]string{"/static": dir}
+}
+
+// serveStatic returns recorded response of static request
+func serveStatic(url, acceptEncoding string) *httptest.ResponseRecorder {
+	w := httptest.NewRecorder()
+	r := httptest.NewRequest("GET", url, nil)
+	if acceptEncoding != "" {
+		r.Header.Set("Accept-Encoding", acceptEncoding)
+	}
+	ctx := context.NewContext()
+	ctx.Reset(w, r)
+	serveStaticRoutes(ctx)
+	return w
+}
+
+func TestServeStaticSidecar(t *testing.T) {
+	small := []byte("console.log(1)")
+	large := bytes.Repeat([]byte("x"), 2*defaultStaticCacheFileSize)
+	newStaticTest(t, map[string][]byte{
+		"small.js":    small,
+		"small.js.br": []byte("small br"),
+		"large.js":    large,
+		"large.js.gz": []byte("large gz"),
+	})
+
+	tests := []struct {
+		url, accept, encoding, body string
+	}{
+		{"/static/small.js", "br, gzip", "br", "small br"},
+		{"/static/small.js", "", "", string(small)},
+		{"/static/large.js", "gzip", "gzip", "large gz"},
+		{"/static/large.js", "br", "", string(large)},
+	}
+	for _, tt := range tests {
+		w := serveStatic(tt.url, tt.accept)
+		if w.Code != 200 {
+			t.Fatalf("%s %q: status %d", tt.url, tt.accept, w.Code)
+		}
+		if got := w.Header().Get("Content-Encoding"); got != tt.encoding {
+			t.Errorf("%s %q: Content-Encoding = %q, want %q", tt.url, tt.accept, got, tt.encoding)
+		}
+		if w.Body.String() != tt.body {
+			t.Errorf("%s %q: body of %d bytes, want %d", tt.url, tt.accept, w.Body.Len(), len(tt.body))
+		}
+	}
+}
+
+// fingerprinted urls are immutable whichever way file is served
+func TestServeStaticFingerprint(t *testing.T) {
+	newStaticTest(t, map[string][]byte{
+		"app.css":   []byte("body{}"),
+		"app.js":    []byte("console.log(1)"),
+		"app.js.br": []byte("app br"),
+		"large.js":  bytes.Repeat([]byte("x"), 2*defaultStaticCacheFileSize),
+	})
+
+	for _, tt := range []struct{ path, accept string }{
+		{"/static/app.css", ""},
+		{"/static/app.js", "br"},
+		{"/static/large.js", ""},
+	} {
+		url, err := AssetURL(tt.path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		w := serveStatic(url, tt.accept)
+		if cc := w.Header().Get("Cache-Control"); cc != "public, max-age=31536000, immutable" {
+			t.Errorf("%s: Cache-Control = %q", url, cc)
+		}
+		w = serveStatic(tt.path+"?v=stale", tt.accept)
+		if cc := w.Header().Get("Cache-Control"); cc != "" {
+			t.Errorf("%s?v=stale: Cache-Control = %q", tt.path, cc)
+		}
+	}
+}